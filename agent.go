@@ -0,0 +1,214 @@
+package request
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Agent holds the configuration shared by many requests, such as the base
+// URL, default headers, authentication, cookies, timeout and transport.
+//
+// Unlike a Client, which can only send one request, an Agent is safe for
+// concurrent use by multiple goroutines. Its To, Get, Post, Put and Delete
+// methods spawn a fresh Client that starts with a copy of the agent's
+// configuration, so changes to the spawned Client never affect the Agent or
+// other requests. Spawned clients share the agent's transport, and therefore
+// its connection pool.
+type Agent struct {
+	mu          sync.RWMutex
+	cli         *http.Client
+	base        *url.URL
+	header      http.Header
+	basicAuth   *basicAuthInfo
+	cookies     []*http.Cookie
+	retry       *RetryPolicy
+	limiter     *Limiter
+	limits      responseLimits
+	middlewares []Middleware
+	err         error
+}
+
+// NewAgent returns a new instance of Agent.
+func NewAgent() *Agent {
+	return &Agent{
+		cli:     new(http.Client),
+		header:  make(http.Header),
+		cookies: make([]*http.Cookie, 0),
+	}
+}
+
+// BaseURL sets the URL which relative URLs of the spawned requests are
+// resolved against, so agent.Get("users") with the base URL
+// "http://mysite.com/api/" requests "http://mysite.com/api/users".
+func (a *Agent) BaseURL(URL string) *Agent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	u, err := url.Parse(URL)
+
+	if err != nil {
+		a.err = err
+		return a
+	}
+
+	a.base = u
+
+	return a
+}
+
+// Set sets the default header entries associated with key to the single
+// element value. It replaces any existing values associated with key.
+func (a *Agent) Set(key, value string) *Agent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.header.Set(key, value)
+
+	return a
+}
+
+// Add adds the key, value pair to the default header. It appends to any
+// existing values associated with key.
+func (a *Agent) Add(key, value string) *Agent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.header.Add(key, value)
+
+	return a
+}
+
+// Header sets all key, value pairs in h to the default header, it replaces
+// any existing values associated with key.
+func (a *Agent) Header(h http.Header) *Agent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for k, v := range h {
+		a.header[k] = v
+	}
+
+	return a
+}
+
+// Auth sets the default HTTP Basic Authentication username and password.
+func (a *Agent) Auth(name, password string) *Agent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.basicAuth = &basicAuthInfo{name: name, password: password}
+
+	return a
+}
+
+// Cookie adds the cookie to every spawned request.
+func (a *Agent) Cookie(cookie *http.Cookie) *Agent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.cookies = append(a.cookies, cookie)
+
+	return a
+}
+
+// Timeout specifies the default time limit for the spawned requests, see
+// Client.Timeout for details.
+func (a *Agent) Timeout(timeout time.Duration) *Agent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.cli.Timeout = timeout
+
+	return a
+}
+
+// Redirects sets the default max redirects count for the spawned requests.
+func (a *Agent) Redirects(count int) *Agent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.cli.CheckRedirect = maxRedirects(count).check
+
+	return a
+}
+
+// Transport sets the http.RoundTripper used by the spawned requests. If not
+// set, http.DefaultTransport is used.
+func (a *Agent) Transport(transport http.RoundTripper) *Agent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.cli.Transport = transport
+
+	return a
+}
+
+// Proxy sets the address of the proxy which used by the spawned requests.
+func (a *Agent) Proxy(addr string) *Agent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	transport, err := proxyTransport(addr)
+
+	if err != nil {
+		a.err = err
+		return a
+	}
+
+	if transport != nil {
+		a.cli.Transport = transport
+	}
+
+	return a
+}
+
+// New returns a new instance of Client with the agent's configuration.
+func (a *Agent) New() *Client {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	c := New()
+	cli := *a.cli
+	c.cli = &cli
+	c.base = a.base
+	c.basicAuth = a.basicAuth
+	c.retry = a.retry
+	c.limiter = a.limiter
+	c.limits = a.limits
+	c.middlewares = append(c.middlewares, a.middlewares...)
+	c.err = a.err
+	c.cookies = append(c.cookies, a.cookies...)
+
+	for k, v := range a.header {
+		c.header[k] = append([]string(nil), v...)
+	}
+
+	return c
+}
+
+// To equals New().To(method, URL) .
+func (a *Agent) To(method string, URL string) *Client {
+	return a.New().To(method, URL)
+}
+
+// Get equals New().Get(URL) .
+func (a *Agent) Get(URL string) *Client {
+	return a.New().Get(URL)
+}
+
+// Post equals New().Post(URL) .
+func (a *Agent) Post(URL string) *Client {
+	return a.New().Post(URL)
+}
+
+// Put equals New().Put(URL) .
+func (a *Agent) Put(URL string) *Client {
+	return a.New().Put(URL)
+}
+
+// Delete equals New().Delete(URL) .
+func (a *Agent) Delete(URL string) *Client {
+	return a.New().Delete(URL)
+}
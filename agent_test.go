@@ -0,0 +1,121 @@
+package request
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type AgentSuite struct {
+	suite.Suite
+
+	server *httptest.Server
+	a      *Agent
+}
+
+func (s *AgentSuite) SetupTest() {
+	s.server = httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		name, _, _ := req.BasicAuth()
+		cookies := make(map[string]string)
+
+		for _, cookie := range req.Cookies() {
+			cookies[cookie.Name] = cookie.Value
+		}
+
+		res.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(res).Encode(map[string]interface{}{
+			"path":    req.URL.Path,
+			"header":  req.Header.Get("X-Test-Key"),
+			"user":    name,
+			"cookies": cookies,
+		})
+	}))
+
+	s.a = NewAgent()
+}
+
+func (s *AgentSuite) TearDownTest() {
+	s.server.Close()
+}
+
+func (s *AgentSuite) TestBaseURL() {
+	j, err := s.a.
+		BaseURL(s.server.URL + "/api/").
+		Get("users").
+		JSON()
+
+	s.Nil(err)
+	s.Equal("/api/users", GetPath(j, "path").(string))
+
+	j, err = s.a.Get(s.server.URL + "/absolute").JSON()
+
+	s.Nil(err)
+	s.Equal("/absolute", GetPath(j, "path").(string))
+}
+
+func (s *AgentSuite) TestInvalidBaseURL() {
+	_, err := s.a.
+		BaseURL("%").
+		Get(s.server.URL).
+		End()
+
+	s.NotNil(err)
+}
+
+func (s *AgentSuite) TestDefaults() {
+	j, err := s.a.
+		Set("X-Test-Key", "X-TEST-VALUE").
+		Auth("user", "passwd").
+		Cookie(&http.Cookie{Name: "k1", Value: "v1"}).
+		Get(s.server.URL).
+		JSON()
+
+	s.Nil(err)
+	s.Equal("X-TEST-VALUE", GetPath(j, "header").(string))
+	s.Equal("user", GetPath(j, "user").(string))
+	s.Equal("v1", GetPath(j, "cookies", "k1").(string))
+}
+
+func (s *AgentSuite) TestIsolation() {
+	s.a.Set("X-Test-Key", "agent").Timeout(time.Minute)
+
+	c := s.a.Get(s.server.URL).Set("X-Test-Key", "client").Timeout(time.Second)
+
+	s.Equal(time.Second, c.cli.Timeout)
+	s.Equal(time.Minute, s.a.cli.Timeout)
+
+	j, err := s.a.Get(s.server.URL).JSON()
+
+	s.Nil(err)
+	s.Equal("agent", GetPath(j, "header").(string))
+}
+
+func (s *AgentSuite) TestConcurrent() {
+	s.a.BaseURL(s.server.URL).Set("X-Test-Key", "X-TEST-VALUE")
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			j, err := s.a.Get("/concurrent").JSON()
+
+			s.Nil(err)
+			s.Equal("X-TEST-VALUE", GetPath(j, "header").(string))
+		}()
+	}
+
+	wg.Wait()
+}
+
+func TestAgent(t *testing.T) {
+	suite.Run(t, new(AgentSuite))
+}
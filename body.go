@@ -0,0 +1,312 @@
+package request
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-http-utils/headers"
+)
+
+// requestBody returns the request body, the function which returns a new
+// copy of it and the size of it which is -1 if unknown. The body is nil if
+// the request has no body, and the function is nil if the body can only be
+// read once.
+func (c *Client) requestBody() (io.ReadCloser, func() (io.ReadCloser, error), int64, error) {
+	var getBody func() (io.ReadCloser, error)
+	var size int64
+	replayable := true
+
+	switch {
+	case len(c.parts) != 0:
+		var contentType string
+		contentType, getBody, size, replayable = c.multipartBody()
+		c.Type(contentType)
+	case c.body != nil:
+		var err error
+
+		if getBody, size, err = readerBody(c.body); err != nil {
+			return nil, nil, 0, err
+		}
+
+		if getBody == nil {
+			body := c.body
+			getBody, size, replayable = func() (io.ReadCloser, error) {
+				if producer, ok := body.(*producerBody); ok {
+					return producer, nil
+				}
+
+				return ioutil.NopCloser(body), nil
+			}, -1, false
+		}
+	default:
+		form := c.formVals.Encode()
+
+		if form == "" {
+			return nil, nil, 0, nil
+		}
+
+		getBody, size, _ = readerBody(strings.NewReader(form))
+	}
+
+	getBody, size = c.compressBody(getBody, size)
+
+	if size != 0 {
+		getBody = c.wrapBody(getBody, size)
+	}
+
+	body, err := getBody()
+
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	if !replayable {
+		getBody = nil
+	}
+
+	return body, getBody, size, nil
+}
+
+// wrapBody wraps the request body returned by getBody to report the upload
+// progress and limit the bandwidth.
+func (c *Client) wrapBody(getBody func() (io.ReadCloser, error), size int64) func() (io.ReadCloser, error) {
+	ctx := c.ctx
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	progress, limiter := c.uploadProgress, c.limiter
+
+	return func() (io.ReadCloser, error) {
+		body, err := getBody()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if progress != nil {
+			body = newProgressBody(body, size, progress)
+		}
+
+		if limiter != nil {
+			body = &limitedBody{ReadCloser: body, ctx: ctx, limiter: limiter}
+		}
+
+		return body, nil
+	}
+}
+
+// readerBody returns the function which returns a new reader of the content
+// of r and the size of it, if r is a *bytes.Buffer, *bytes.Reader,
+// *strings.Reader, seekable io.Seeker or a body of SendFile or BodyFunc.
+// Otherwise r can only be read once and nil is returned.
+func readerBody(r io.Reader) (func() (io.ReadCloser, error), int64, error) {
+	var size int64
+	var getBody func() (io.ReadCloser, error)
+
+	switch v := r.(type) {
+	case *bytes.Buffer:
+		b := v.Bytes()
+		size = int64(len(b))
+		getBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(b)), nil
+		}
+	case *bytes.Reader:
+		snapshot := *v
+		size = int64(v.Len())
+		getBody = func() (io.ReadCloser, error) {
+			r := snapshot
+			return ioutil.NopCloser(&r), nil
+		}
+	case *strings.Reader:
+		snapshot := *v
+		size = int64(v.Len())
+		getBody = func() (io.ReadCloser, error) {
+			r := snapshot
+			return ioutil.NopCloser(&r), nil
+		}
+	case *fileBody:
+		size = v.size
+		getBody = func() (io.ReadCloser, error) {
+			return &fileBody{path: v.path, size: v.size}, nil
+		}
+	case *producerBody:
+		if v.once {
+			return nil, -1, nil
+		}
+
+		return func() (io.ReadCloser, error) {
+			return newProducerBody(v.produce, false), nil
+		}, -1, nil
+	case io.Seeker:
+		// Files such as pipes, sockets and stdin are io.Seekers which can't
+		// seek, they can only be read once.
+		offset, err := v.Seek(0, io.SeekCurrent)
+
+		if err != nil {
+			return nil, -1, nil
+		}
+
+		end, err := v.Seek(0, io.SeekEnd)
+
+		if err != nil {
+			return nil, -1, err
+		}
+
+		size = end - offset
+		getBody = func() (io.ReadCloser, error) {
+			if _, err := v.Seek(offset, io.SeekStart); err != nil {
+				return nil, err
+			}
+
+			return ioutil.NopCloser(io.LimitReader(r, size)), nil
+		}
+	default:
+		return nil, -1, nil
+	}
+
+	if size == 0 {
+		getBody = func() (io.ReadCloser, error) {
+			return http.NoBody, nil
+		}
+	}
+
+	return getBody, size, nil
+}
+
+// Body sets the request body which is read from r. The body can be replayed
+// when the request is retried or redirected if r is a *bytes.Buffer,
+// *bytes.Reader, *strings.Reader or an io.Seeker, whose content is read from
+// its current offset, and the "Content-Length" is set for them. An io.Seeker
+// which can't seek, such as a pipe or stdin, is sent once with the chunked
+// transfer encoding. r is never closed.
+func (c *Client) Body(r io.Reader) *Client {
+	if c.body != nil || len(c.parts) != 0 {
+		c.err = ErrBodyAlreadySet
+		return c
+	}
+
+	c.body = r
+
+	return c
+}
+
+// SendFile sets the request body which is streamed from the file at path,
+// with the "Content-Length" of the file size. The "Content-Type" is guessed
+// from the extension of the file if it is not set yet. The file is opened
+// each time the request is sent.
+func (c *Client) SendFile(path string) *Client {
+	if c.body != nil || len(c.parts) != 0 {
+		c.err = ErrBodyAlreadySet
+		return c
+	}
+
+	info, err := os.Stat(path)
+
+	if err != nil {
+		c.err = err
+		return c
+	}
+
+	if c.header.Get(headers.ContentType) == "" {
+		if typ := mime.TypeByExtension(filepath.Ext(path)); typ != "" {
+			c.Type(typ)
+		}
+	}
+
+	c.body = &fileBody{path: path, size: info.Size()}
+
+	return c
+}
+
+// BodyFunc sets the request body which is produced by fn, and sent with the
+// chunked transfer encoding. fn is called in another goroutine each time the
+// request is sent, and the error returned by fn aborts the request.
+func (c *Client) BodyFunc(fn func(w io.Writer) error) *Client {
+	if c.body != nil || len(c.parts) != 0 {
+		c.err = ErrBodyAlreadySet
+		return c
+	}
+
+	c.body = newProducerBody(fn, false)
+
+	return c
+}
+
+// BodyChan sets the request body which is sent with the chunked transfer
+// encoding, each slice received from ch is sent as soon as it is received,
+// until ch is closed. The body can not be replayed, so the request is never
+// retried.
+func (c *Client) BodyChan(ch <-chan []byte) *Client {
+	if c.body != nil || len(c.parts) != 0 {
+		c.err = ErrBodyAlreadySet
+		return c
+	}
+
+	c.body = newProducerBody(func(w io.Writer) error {
+		for b := range ch {
+			if _, err := w.Write(b); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, true)
+
+	return c
+}
+
+// fileBody reads the file at path, the file is opened on the first Read.
+type fileBody struct {
+	path string
+	size int64
+	file *os.File
+}
+
+func (b *fileBody) Read(p []byte) (int, error) {
+	if b.file == nil {
+		file, err := os.Open(b.path)
+
+		if err != nil {
+			return 0, err
+		}
+
+		b.file = file
+	}
+
+	return b.file.Read(p)
+}
+
+func (b *fileBody) Close() error {
+	if b.file == nil {
+		return nil
+	}
+
+	return b.file.Close()
+}
+
+// producerBody reads the content written by produce. once reports whether
+// produce can only be called once.
+type producerBody struct {
+	*pipeBody
+	produce func(w io.Writer) error
+	once    bool
+}
+
+func newProducerBody(produce func(w io.Writer) error, once bool) *producerBody {
+	pr, pw := io.Pipe()
+
+	return &producerBody{
+		pipeBody: &pipeBody{pr: pr, pw: pw, write: produce},
+		produce:  produce,
+		once:     once,
+	}
+}
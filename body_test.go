@@ -0,0 +1,212 @@
+package request
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-http-utils/headers"
+	"github.com/stretchr/testify/suite"
+)
+
+type BodySuite struct {
+	suite.Suite
+
+	dir    string
+	server *httptest.Server
+}
+
+func (s *BodySuite) SetupTest() {
+	var err error
+	s.dir, err = ioutil.TempDir("", "request")
+	s.Nil(err)
+
+	s.server = httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/redirect" {
+			http.Redirect(res, req, "/", http.StatusTemporaryRedirect)
+			return
+		}
+
+		b, _ := ioutil.ReadAll(req.Body)
+
+		res.Header().Set("X-Content-Length", fmt.Sprint(req.ContentLength))
+		res.Header().Set("X-Transfer-Encoding", strings.Join(req.TransferEncoding, ","))
+		res.Header().Set("X-Content-Type", req.Header.Get(headers.ContentType))
+		res.Write(b)
+	}))
+}
+
+func (s *BodySuite) TearDownTest() {
+	s.server.Close()
+	os.RemoveAll(s.dir)
+}
+
+func (s *BodySuite) TestBody() {
+	res, err := Post(s.server.URL + "/redirect").Body(strings.NewReader("hello")).End()
+	s.Nil(err)
+
+	text, err := res.Text()
+
+	s.Nil(err)
+	s.Equal("hello", text)
+	s.Equal("5", res.Header.Get("X-Content-Length"))
+}
+
+func (s *BodySuite) TestBodySeeker() {
+	path := filepath.Join(s.dir, "a.txt")
+	s.Nil(ioutil.WriteFile(path, []byte("skip:hello"), 0644))
+
+	file, err := os.Open(path)
+	s.Nil(err)
+	defer file.Close()
+
+	file.Seek(5, io.SeekStart)
+
+	res, err := Post(s.server.URL + "/redirect").Body(file).End()
+	s.Nil(err)
+
+	text, err := res.Text()
+
+	s.Nil(err)
+	s.Equal("hello", text)
+	s.Equal("5", res.Header.Get("X-Content-Length"))
+}
+
+func (s *BodySuite) TestBodyOnce() {
+	r := struct{ io.Reader }{strings.NewReader("hello")}
+
+	req, err := Post(s.server.URL).Body(r).Req()
+
+	s.Nil(err)
+	s.Nil(req.GetBody)
+
+	res, err := Post(s.server.URL).Body(r).End()
+	s.Nil(err)
+
+	text, err := res.Text()
+
+	s.Nil(err)
+	s.Equal("hello", text)
+	s.Equal("chunked", res.Header.Get("X-Transfer-Encoding"))
+}
+
+func (s *BodySuite) TestBodyPipe() {
+	pr, pw, err := os.Pipe()
+	s.Nil(err)
+	defer pr.Close()
+
+	go func() {
+		pw.Write([]byte("hello"))
+		pw.Close()
+	}()
+
+	res, err := Post(s.server.URL).Body(pr).End()
+	s.Nil(err)
+
+	text, err := res.Text()
+
+	s.Nil(err)
+	s.Equal("hello", text)
+	s.Equal("chunked", res.Header.Get("X-Transfer-Encoding"))
+}
+
+func (s *BodySuite) TestSendFile() {
+	path := filepath.Join(s.dir, "a.json")
+	s.Nil(ioutil.WriteFile(path, []byte(`{"k":"v"}`), 0644))
+
+	var attempts int32
+	server := failingServer(1, http.StatusBadGateway, &attempts)
+	defer server.Close()
+
+	text, err := Post(server.URL).SendFile(path).Retry(NewRetryPolicy(2)).Text()
+
+	s.Nil(err)
+	s.Equal(`{"k":"v"}`, text)
+	s.Equal(int32(2), attempts)
+
+	res, err := Post(s.server.URL).SendFile(path).End()
+	s.Nil(err)
+
+	s.Equal("9", res.Header.Get("X-Content-Length"))
+	s.Equal("application/json", res.Header.Get("X-Content-Type"))
+}
+
+func (s *BodySuite) TestSendFileNotExists() {
+	_, err := Post(s.server.URL).SendFile(filepath.Join(s.dir, "not-exists")).End()
+
+	s.True(os.IsNotExist(err))
+}
+
+func (s *BodySuite) TestBodyFunc() {
+	res, err := Post(s.server.URL + "/redirect").
+		BodyFunc(func(w io.Writer) error {
+			for i := 0; i < 3; i++ {
+				fmt.Fprintf(w, "chunk%d;", i)
+			}
+
+			return nil
+		}).
+		End()
+	s.Nil(err)
+
+	text, err := res.Text()
+
+	s.Nil(err)
+	s.Equal("chunk0;chunk1;chunk2;", text)
+	s.Equal("chunked", res.Header.Get("X-Transfer-Encoding"))
+}
+
+func (s *BodySuite) TestBodyFuncError() {
+	errProduce := errors.New("produce")
+
+	_, err := Post(s.server.URL).
+		BodyFunc(func(w io.Writer) error {
+			w.Write([]byte("chunk"))
+			return errProduce
+		}).
+		End()
+
+	s.ErrorIs(err, errProduce)
+}
+
+func (s *BodySuite) TestBodyChan() {
+	ch := make(chan []byte)
+
+	go func() {
+		for i := 0; i < 3; i++ {
+			ch <- []byte(fmt.Sprintf("chunk%d;", i))
+		}
+
+		close(ch)
+	}()
+
+	text, err := Post(s.server.URL).BodyChan(ch).Text()
+
+	s.Nil(err)
+	s.Equal("chunk0;chunk1;chunk2;", text)
+}
+
+func (s *BodySuite) TestBodyAlreadySet() {
+	_, err := Post(s.server.URL).Send("hello").Body(strings.NewReader("hello")).End()
+	s.Equal(ErrBodyAlreadySet, err)
+
+	_, err = Post(s.server.URL).Body(strings.NewReader("hello")).Send("hello").End()
+	s.Equal(ErrBodyAlreadySet, err)
+
+	_, err = Post(s.server.URL).AttachBytes("file", "a.txt", "", nil).SendFile("a.txt").End()
+	s.Equal(ErrBodyAlreadySet, err)
+
+	_, err = Post(s.server.URL).BodyChan(nil).AttachBytes("file", "a.txt", "", nil).End()
+	s.Equal(ErrBodyAlreadySet, err)
+}
+
+func TestBody(t *testing.T) {
+	suite.Run(t, new(BodySuite))
+}
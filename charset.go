@@ -0,0 +1,147 @@
+package request
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"strings"
+
+	"github.com/go-http-utils/headers"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// maxSniffBytes is the max number of bytes which are sniffed for the HTML
+// meta charset declaration, as the HTML standard does.
+const maxSniffBytes = 1024
+
+// Charset forces Response.Text to decode the body from the charset label,
+// such as "shift_jis", "gbk" or "iso-8859-1", instead of detecting it.
+func (c *Client) Charset(label string) *Client {
+	if e, _ := charset.Lookup(label); e == nil {
+		c.err = fmt.Errorf("request: unknown charset %q", label)
+		return c
+	}
+
+	c.charset = label
+
+	return c
+}
+
+// NoTranscode makes Response.Text return the body as is, without transcoding
+// it to UTF-8.
+func (c *Client) NoTranscode() *Client {
+	c.noTranscode = true
+
+	return c
+}
+
+// transcode transcodes b to UTF-8. The encoding of b is the charset set by
+// Client.Charset, or the charset of the "Content-Type" header, or detected
+// from the byte order mark, or declared by the meta tag of a HTML body, in
+// that order. b is regarded as UTF-8 if there is none of them.
+func (r *Response) transcode(b []byte) ([]byte, error) {
+	if r.noTranscode {
+		return b, nil
+	}
+
+	typ, params, err := mime.ParseMediaType(r.Header.Get(headers.ContentType))
+
+	if err != nil && err != mime.ErrInvalidMediaParameter {
+		typ = ""
+	}
+
+	e := lookupCharset(r.charset)
+
+	if e == nil {
+		e = lookupCharset(params["charset"])
+	}
+
+	if e == nil {
+		e = bomEncoding(b)
+	}
+
+	if e == nil && (typ == "text/html" || typ == "application/xhtml+xml") {
+		e = lookupCharset(sniffHTMLCharset(b))
+	}
+
+	if e == nil || e == unicode.UTF8 {
+		return bytes.TrimPrefix(b, utf8BOM), nil
+	}
+
+	return e.NewDecoder().Bytes(b)
+}
+
+func lookupCharset(label string) encoding.Encoding {
+	if label == "" {
+		return nil
+	}
+
+	e, _ := charset.Lookup(label)
+
+	return e
+}
+
+var utf8BOM = []byte{0xef, 0xbb, 0xbf}
+
+// bomEncoding returns the encoding detected from the byte order mark of b,
+// the returned decoder removes the byte order mark.
+func bomEncoding(b []byte) encoding.Encoding {
+	switch {
+	case bytes.HasPrefix(b, utf8BOM):
+		return unicode.UTF8
+	case bytes.HasPrefix(b, []byte{0xfe, 0xff}):
+		return unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM)
+	case bytes.HasPrefix(b, []byte{0xff, 0xfe}):
+		return unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM)
+	}
+
+	return nil
+}
+
+// sniffHTMLCharset returns the charset declared by the meta tag in the
+// beginning of the HTML document b, or "" if there is none.
+func sniffHTMLCharset(b []byte) string {
+	if len(b) > maxSniffBytes {
+		b = b[:maxSniffBytes]
+	}
+
+	z := html.NewTokenizer(bytes.NewReader(b))
+
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return ""
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+
+			if string(name) != "meta" || !hasAttr {
+				continue
+			}
+
+			var httpEquiv, content string
+
+			for hasAttr {
+				var key, val []byte
+				key, val, hasAttr = z.TagAttr()
+
+				switch strings.ToLower(string(key)) {
+				case "charset":
+					return strings.TrimSpace(string(val))
+				case "http-equiv":
+					httpEquiv = strings.ToLower(string(val))
+				case "content":
+					content = string(val)
+				}
+			}
+
+			if httpEquiv == "content-type" {
+				if _, params, err := mime.ParseMediaType(content); err == nil && params["charset"] != "" {
+					return params["charset"]
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package request
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-http-utils/headers"
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+type CharsetSuite struct {
+	suite.Suite
+
+	server *httptest.Server
+}
+
+func (s *CharsetSuite) SetupTest() {
+	sjis, _ := japanese.ShiftJIS.NewEncoder().String("こんにちは")
+	gbk, _ := simplifiedchinese.GBK.NewEncoder().String("你好")
+	latin1, _ := charmap.ISO8859_1.NewEncoder().String("café")
+	utf16, _ := unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewEncoder().String("héllo")
+
+	bodies := map[string][2]string{
+		"/sjis":       {"text/plain; charset=Shift_JIS", sjis},
+		"/gbk":        {"text/plain; charset=gbk", gbk},
+		"/latin1":     {"text/plain; charset=iso-8859-1", latin1},
+		"/utf8":       {"text/plain", "\xef\xbb\xbfhéllo"},
+		"/utf16":      {"text/plain", utf16},
+		"/meta":       {"text/html", `<html><head><meta charset="shift_jis"></head>` + sjis},
+		"/http-equiv": {"text/html", `<meta http-equiv="Content-Type" content="text/html; charset=gbk">` + gbk},
+		"/unknown":    {"text/plain", gbk},
+	}
+
+	s.server = httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		body := bodies[req.URL.Path]
+
+		res.Header().Set(headers.ContentType, body[0])
+		res.Write([]byte(body[1]))
+	}))
+}
+
+func (s *CharsetSuite) TearDownTest() {
+	s.server.Close()
+}
+
+func (s *CharsetSuite) TestText() {
+	for path, expected := range map[string]string{
+		"/sjis":       "こんにちは",
+		"/gbk":        "你好",
+		"/latin1":     "café",
+		"/utf8":       "héllo",
+		"/utf16":      "héllo",
+		"/meta":       `<html><head><meta charset="shift_jis"></head>こんにちは`,
+		"/http-equiv": `<meta http-equiv="Content-Type" content="text/html; charset=gbk">你好`,
+	} {
+		text, err := Get(s.server.URL + path).Text()
+
+		s.Nil(err)
+		s.Equal(expected, text, path)
+	}
+}
+
+func (s *CharsetSuite) TestCharset() {
+	text, err := Get(s.server.URL + "/unknown").Charset("gbk").Text()
+
+	s.Nil(err)
+	s.Equal("你好", text)
+
+	_, err = Get(s.server.URL + "/unknown").Charset("not-a-charset").Text()
+
+	s.EqualError(err, `request: unknown charset "not-a-charset"`)
+}
+
+func (s *CharsetSuite) TestNoTranscode() {
+	gbk, _ := simplifiedchinese.GBK.NewEncoder().String("你好")
+
+	text, err := Get(s.server.URL + "/gbk").NoTranscode().Text()
+
+	s.Nil(err)
+	s.Equal(gbk, text)
+}
+
+func TestCharset(t *testing.T) {
+	suite.Run(t, new(CharsetSuite))
+}
@@ -0,0 +1,103 @@
+package request
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Codec marshals and unmarshals the values of a media type.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// jsonOptions are the options of decoding JSON.
+type jsonOptions struct {
+	useNumber             bool
+	disallowUnknownFields bool
+}
+
+type jsonCodec struct {
+	options jsonOptions
+}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (c jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	if c.options == (jsonOptions{}) {
+		return json.Unmarshal(data, v)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	if c.options.useNumber {
+		dec.UseNumber()
+	}
+
+	if c.options.disallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+
+	// Rejects the trailing data as json.Unmarshal does.
+	if _, err := dec.Token(); err != io.EOF {
+		return errTrailingData
+	}
+
+	return nil
+}
+
+var errTrailingData = errors.New("request: invalid data after the top-level JSON value")
+
+var codecs = struct {
+	sync.RWMutex
+	m map[string]Codec
+}{
+	m: map[string]Codec{
+		"application/json": jsonCodec{},
+	},
+}
+
+// RegisterCodec registers the codec used for the media type by Send and
+// Response.Decode, it replaces the codec already registered for the media
+// type. A codec registered for "application/xxx" is also used for the media
+// types with the "+xxx" structured syntax suffix, so the "application/json"
+// codec is used for "application/vnd.api+json" too.
+//
+// RegisterCodec is safe for concurrent use, but it is usually called in an
+// init function.
+func RegisterCodec(mediaType string, codec Codec) {
+	codecs.Lock()
+	defer codecs.Unlock()
+
+	codecs.m[strings.ToLower(mediaType)] = codec
+}
+
+// codecFor returns the codec registered for the lower-cased media type typ.
+func codecFor(typ string) (Codec, bool) {
+	codecs.RLock()
+	defer codecs.RUnlock()
+
+	if codec, ok := codecs.m[typ]; ok {
+		return codec, true
+	}
+
+	if isJSON(typ) {
+		typ = "application/json"
+	} else if i := strings.LastIndex(typ, "+"); i >= 0 {
+		typ = "application/" + typ[i+1:]
+	}
+
+	codec, ok := codecs.m[typ]
+
+	return codec, ok
+}
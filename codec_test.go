@@ -0,0 +1,190 @@
+package request
+
+import (
+	encjson "encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-http-utils/headers"
+	"github.com/stretchr/testify/suite"
+)
+
+// csvCodec marshals a []string into a comma-separated line.
+type csvCodec struct{}
+
+func (csvCodec) Marshal(v interface{}) ([]byte, error) {
+	fields, ok := v.([]string)
+
+	if !ok {
+		return nil, errors.New("csv: not a []string")
+	}
+
+	return []byte(strings.Join(fields, ",")), nil
+}
+
+func (csvCodec) Unmarshal(data []byte, v interface{}) error {
+	fields, ok := v.(*[]string)
+
+	if !ok {
+		return errors.New("csv: not a *[]string")
+	}
+
+	*fields = strings.Split(string(data), ",")
+
+	return nil
+}
+
+type CodecSuite struct {
+	suite.Suite
+
+	server *httptest.Server
+}
+
+func (s *CodecSuite) SetupSuite() {
+	RegisterCodec("text/CSV", csvCodec{})
+	RegisterType("csv", "text/csv")
+}
+
+func (s *CodecSuite) SetupTest() {
+	// The server echoes the request body with the given content type.
+	s.server = httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		b, _ := ioutil.ReadAll(req.Body)
+
+		res.Header().Set(headers.ContentType, req.URL.Query().Get("type"))
+		res.Header().Set("X-Content-Type", req.Header.Get(headers.ContentType))
+		res.Write(b)
+	}))
+}
+
+func (s *CodecSuite) TearDownTest() {
+	s.server.Close()
+}
+
+func (s *CodecSuite) TestSendAndDecode() {
+	var fields []string
+
+	res, err := Post(s.server.URL + "?type=text/csv%3Bcharset=utf-8").
+		Type("csv").
+		Send([]string{"a", "b"}).
+		End()
+
+	s.Nil(err)
+	s.Equal("text/csv", res.Header.Get("X-Content-Type"))
+	s.Nil(res.Decode(&fields))
+	s.Equal([]string{"a", "b"}, fields)
+}
+
+func (s *CodecSuite) TestSendDefaultJSON() {
+	res, err := Post(s.server.URL + "?type=application/vnd.api%2Bjson").
+		Type("text").
+		Send(map[string]string{"k1": "v1"}).
+		End()
+
+	s.Nil(err)
+	s.Equal("application/json", res.Header.Get("X-Content-Type"))
+
+	var v map[string]string
+
+	s.Nil(res.Decode(&v))
+	s.Equal("v1", v["k1"])
+}
+
+func (s *CodecSuite) TestClientDecode() {
+	var v map[string]string
+
+	err := Post(s.server.URL + "?type=application/json").
+		Send(map[string]string{"k1": "v1"}).
+		Decode(&v)
+
+	s.Nil(err)
+	s.Equal("v1", v["k1"])
+}
+
+func (s *CodecSuite) TestNoCodec() {
+	var v map[string]string
+
+	err := Post(s.server.URL + "?type=text/plain").
+		Send(map[string]string{"k1": "v1"}).
+		Decode(&v)
+
+	s.Equal(ErrNoCodec, err)
+
+	err = Post(s.server.URL + "?type=text/plain").
+		Send(map[string]string{"k1": "v1"}).
+		LenientJSON().
+		Decode(&v)
+
+	s.Nil(err)
+	s.Equal("v1", v["k1"])
+}
+
+func (s *CodecSuite) TestMarshalError() {
+	_, err := Post(s.server.URL).
+		Type("csv").
+		Send(1).
+		End()
+
+	s.EqualError(err, "csv: not a []string")
+}
+
+func (s *CodecSuite) TestUseNumber() {
+	url := s.server.URL + "?type=application/json"
+	body := `{"id":12345678901234567890}`
+
+	json, err := Post(url).Send(body).JSON()
+
+	s.Nil(err)
+	s.Equal(float64(12345678901234567890), GetPath(json, "id"))
+
+	json, err = Post(url).Send(body).UseNumber().JSON()
+
+	s.Nil(err)
+	s.Equal(encjson.Number("12345678901234567890"), GetPath(json, "id"))
+
+	var v struct{ ID interface{} }
+
+	s.Nil(Post(url).Send(body).UseNumber().Decode(&v))
+	s.Equal(encjson.Number("12345678901234567890"), v.ID)
+}
+
+func (s *CodecSuite) TestDisallowUnknownFields() {
+	url := s.server.URL + "?type=application/vnd.api%2Bjson"
+	var v struct{ ID int }
+
+	s.Nil(Post(url).Send(`{"id":1,"name":"a"}`).Decode(&v))
+	s.Equal(1, v.ID)
+
+	err := Post(url).Send(`{"id":1,"name":"a"}`).DisallowUnknownFields().Decode(&v)
+	s.EqualError(err, `json: unknown field "name"`)
+
+	res, err := Post(url).Send(`{"id":2,"name":"a"}`).End()
+	s.Nil(err)
+
+	_, err = res.DisallowUnknownFields().JSON(&v)
+	s.EqualError(err, `json: unknown field "name"`)
+}
+
+func (s *CodecSuite) TestTrailingData() {
+	url := s.server.URL + "?type=application/json"
+
+	for _, c := range []*Client{
+		Post(url).Send(`{"id":1} {"id":2}`),
+		Post(url).Send(`{"id":1} {"id":2}`).UseNumber(),
+	} {
+		_, err := c.JSON()
+		s.NotNil(err)
+	}
+
+	json, err := Post(url).Send("{\"id\":1}\n").UseNumber().JSON()
+
+	s.Nil(err)
+	s.Equal(encjson.Number("1"), GetPath(json, "id"))
+}
+
+func TestCodec(t *testing.T) {
+	suite.Run(t, new(CodecSuite))
+}
@@ -0,0 +1,85 @@
+package request
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+
+	"github.com/go-http-utils/headers"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compress compresses the request body with the content encoding, which is
+// one of "gzip", "deflate" and "zstd", and sets the "Content-Encoding"
+// header. If threshold is given, the bodies smaller than threshold bytes are
+// sent uncompressed. The compressed body is streamed with the chunked transfer
+// encoding, since its size is unknown until it is fully compressed.
+func (c *Client) Compress(encoding string, threshold ...int64) *Client {
+	if encoders[encoding] == nil {
+		c.err = fmt.Errorf("request: unsupported content encoding %q", encoding)
+		return c
+	}
+
+	c.compression = encoding
+	c.compressThreshold = 0
+
+	if len(threshold) > 0 {
+		c.compressThreshold = threshold[0]
+	}
+
+	return c
+}
+
+// compressBody returns the function which returns a new compressed reader of
+// the body returned by getBody, if the body of size should be compressed.
+func (c *Client) compressBody(getBody func() (io.ReadCloser, error), size int64) (func() (io.ReadCloser, error), int64) {
+	if c.compression == "" || size == 0 || size > 0 && size < c.compressThreshold {
+		return getBody, size
+	}
+
+	encoding := c.compression
+	c.Set(headers.ContentEncoding, encoding)
+
+	return func() (io.ReadCloser, error) {
+		body, err := getBody()
+
+		if err != nil {
+			return nil, err
+		}
+
+		pr, pw := io.Pipe()
+
+		return &pipeBody{pr: pr, pw: pw, write: func(w io.Writer) error {
+			defer body.Close()
+
+			cw, err := encoders[encoding](w)
+
+			if err != nil {
+				return err
+			}
+
+			if _, err = io.Copy(cw, body); err != nil {
+				cw.Close()
+				return err
+			}
+
+			return cw.Close()
+		}}, nil
+	}, -1
+}
+
+// encoders are the functions which return the writers compressing to w, by
+// the content encodings.
+var encoders = map[string]func(w io.Writer) (io.WriteCloser, error){
+	"gzip": func(w io.Writer) (io.WriteCloser, error) {
+		return gzip.NewWriter(w), nil
+	},
+	// deflate is zlib, see decoder.
+	"deflate": func(w io.Writer) (io.WriteCloser, error) {
+		return zlib.NewWriter(w), nil
+	},
+	"zstd": func(w io.Writer) (io.WriteCloser, error) {
+		return zstd.NewWriter(w)
+	},
+}
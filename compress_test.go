@@ -0,0 +1,142 @@
+package request
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-http-utils/headers"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/suite"
+)
+
+type CompressSuite struct {
+	suite.Suite
+
+	body   string
+	server *httptest.Server
+}
+
+func (s *CompressSuite) SetupTest() {
+	s.body = strings.Repeat("request", 1000)
+	s.server = httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		var r io.Reader = req.Body
+		var err error
+
+		switch req.Header.Get(headers.ContentEncoding) {
+		case "gzip":
+			r, err = gzip.NewReader(r)
+		case "deflate":
+			r, err = zlib.NewReader(r)
+		case "zstd":
+			var zr *zstd.Decoder
+			zr, err = zstd.NewReader(r)
+			r = zr
+		}
+
+		if err != nil {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		b, _ := ioutil.ReadAll(r)
+
+		res.Header().Set("X-Content-Encoding", req.Header.Get(headers.ContentEncoding))
+		res.Write(b)
+	}))
+}
+
+func (s *CompressSuite) TearDownTest() {
+	s.server.Close()
+}
+
+func (s *CompressSuite) TestCompress() {
+	for _, encoding := range []string{"gzip", "deflate", "zstd"} {
+		res, err := Post(s.server.URL).Send(s.body).Compress(encoding).End()
+		s.Nil(err)
+
+		text, err := res.Text()
+
+		s.Nil(err)
+		s.Equal(s.body, text)
+		s.Equal(encoding, res.Header.Get("X-Content-Encoding"))
+	}
+}
+
+func (s *CompressSuite) TestCompressForm() {
+	res, err := Post(s.server.URL).
+		Field(url.Values{"k1": []string{"v1"}}).
+		Compress("gzip").
+		End()
+	s.Nil(err)
+
+	text, err := res.Text()
+
+	s.Nil(err)
+	s.Equal("k1=v1", text)
+	s.Equal("gzip", res.Header.Get("X-Content-Encoding"))
+}
+
+func (s *CompressSuite) TestCompressAttach() {
+	res, err := Post(s.server.URL).
+		AttachBytes("file", "a.txt", "", []byte(s.body)).
+		Compress("zstd").
+		End()
+	s.Nil(err)
+
+	text, err := res.Text()
+
+	s.Nil(err)
+	s.Contains(text, s.body)
+	s.Equal("zstd", res.Header.Get("X-Content-Encoding"))
+}
+
+func (s *CompressSuite) TestThreshold() {
+	res, err := Post(s.server.URL).Send("small").Compress("gzip", 1024).End()
+	s.Nil(err)
+
+	text, err := res.Text()
+
+	s.Nil(err)
+	s.Equal("small", text)
+	s.Equal("", res.Header.Get("X-Content-Encoding"))
+
+	res, err = Post(s.server.URL).Send(s.body).Compress("gzip", 1024).End()
+	s.Nil(err)
+	s.Equal("gzip", res.Header.Get("X-Content-Encoding"))
+}
+
+func (s *CompressSuite) TestCompressRetry() {
+	var attempts int32
+	server := failingServer(1, http.StatusBadGateway, &attempts)
+	defer server.Close()
+
+	text, err := Post(server.URL).Send(s.body).Compress("gzip").Retry(NewRetryPolicy(2)).Text()
+
+	s.Nil(err)
+	s.Equal(int32(2), attempts)
+
+	r, err := gzip.NewReader(strings.NewReader(text))
+	s.Nil(err)
+
+	b, err := ioutil.ReadAll(r)
+
+	s.Nil(err)
+	s.Equal(s.body, string(b))
+}
+
+func (s *CompressSuite) TestUnsupportedEncoding() {
+	_, err := Post(s.server.URL).Send(s.body).Compress("br").End()
+
+	s.EqualError(err, `request: unsupported content encoding "br"`)
+}
+
+func TestCompress(t *testing.T) {
+	suite.Run(t, new(CompressSuite))
+}
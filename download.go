@@ -0,0 +1,326 @@
+package request
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/go-http-utils/headers"
+)
+
+const (
+	partSuffix      = ".part"
+	validatorSuffix = ".part.validator"
+)
+
+// ExpectSHA256 sets the expected hex encoded SHA-256 checksum of the file
+// saved by Download.
+func (c *Client) ExpectSHA256(sum string) *Client {
+	b, err := hex.DecodeString(sum)
+
+	if err != nil {
+		c.err = err
+		return c
+	}
+
+	c.sha256 = b
+
+	return c
+}
+
+// Download streams the response body to a temporary file next to path, and
+// atomically renames it to path once the body is fully saved and verified.
+//
+// If a previous download to the same path was interrupted, only the missing
+// part is requested with the "Range" and "If-Range" headers, the whole body
+// is requested again if the remote file has changed since then.
+//
+// The file is verified against the checksum set by ExpectSHA256, and the
+// SHA-256 or MD5 checksum of the "Digest" or "Content-MD5" response headers.
+// If any of them mismatches, the temporary file is removed and
+// ErrChecksumMismatch is returned.
+//
+// A response whose status is neither 200 nor 206 is returned along with a
+// *StatusError, and the file at path is left untouched.
+//
+// If Segments is used, the body is downloaded in several segments at the same
+// time instead, see Segments for details.
+func (c *Client) Download(path string) (*Response, error) {
+	if c.segments > 1 {
+		return c.downloadSegments(path)
+	}
+
+	return c.download(path)
+}
+
+func (c *Client) download(path string) (*Response, error) {
+	partPath := path + partSuffix
+	validatorPath := path + validatorSuffix
+
+	var offset int64
+
+	if info, err := os.Stat(partPath); err == nil {
+		offset = info.Size()
+	}
+
+	validator, err := ioutil.ReadFile(validatorPath)
+
+	if offset > 0 && err == nil && len(validator) > 0 {
+		c.Set(headers.Range, fmt.Sprintf("bytes=%d-", offset))
+		c.Set(headers.IfRange, string(validator))
+	} else {
+		offset = 0
+	}
+
+	c.Set(headers.AcceptEncoding, "identity")
+
+	res, err := c.End()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var file *os.File
+
+	switch {
+	case res.StatusCode == http.StatusPartialContent:
+		start, _, _, ok := parseContentRange(res.Header.Get(headers.ContentRange))
+
+		if !ok || start != offset {
+			res.Body.Close()
+			return res, fmt.Errorf("request: unexpected content range %q",
+				res.Header.Get(headers.ContentRange))
+		}
+
+		file, err = os.OpenFile(partPath, os.O_WRONLY|os.O_APPEND, 0644)
+	case res.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		res.Body.Close()
+
+		// The previous download may be interrupted after the whole body was
+		// saved but before it was renamed.
+		if _, _, total, ok := parseContentRange(res.Header.Get(headers.ContentRange)); !ok || total != offset {
+			os.Remove(partPath)
+			os.Remove(validatorPath)
+			return res, newStatusError(res, nil)
+		}
+	case res.StatusCode == http.StatusOK:
+		offset = 0
+
+		if err = saveValidator(validatorPath, res); err == nil {
+			file, err = os.Create(partPath)
+		}
+	default:
+		b, _ := res.Content()
+		return res, newStatusError(res, b)
+	}
+
+	if err != nil {
+		res.Body.Close()
+		return res, err
+	}
+
+	hashes, err := newChecksums(c.sha256, res)
+
+	if err == nil && offset > 0 && len(hashes) > 0 {
+		err = hashFile(partPath, hashes)
+	}
+
+	if file != nil {
+		if err == nil {
+			var w io.Writer = file
+
+			if len(hashes) > 0 {
+				w = io.MultiWriter(file, hashes)
+			}
+
+			_, err = res.WriteTo(w)
+		} else {
+			res.Body.Close()
+		}
+
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+
+	if err != nil {
+		return res, err
+	}
+
+	if !hashes.verify() {
+		os.Remove(partPath)
+		os.Remove(validatorPath)
+		return res, ErrChecksumMismatch
+	}
+
+	if err = os.Rename(partPath, path); err != nil {
+		return res, err
+	}
+
+	os.Remove(validatorPath)
+
+	return res, nil
+}
+
+// saveValidator saves the strong validator of the response, which is used by
+// the "If-Range" header when the download is resumed.
+func saveValidator(path string, res *Response) error {
+	validator := res.Header.Get(headers.ETag)
+
+	if validator == "" || strings.HasPrefix(validator, "W/") {
+		validator = res.Header.Get(headers.LastModified)
+	}
+
+	if validator == "" {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		return nil
+	}
+
+	return ioutil.WriteFile(path, []byte(validator), 0644)
+}
+
+// parseContentRange parses the "Content-Range" header, total is -1 if it is
+// unknown.
+func parseContentRange(v string) (start, end, total int64, ok bool) {
+	if !strings.HasPrefix(v, "bytes ") {
+		return 0, 0, 0, false
+	}
+
+	v = strings.TrimSpace(v[len("bytes "):])
+	i := strings.IndexByte(v, '/')
+
+	if i < 0 {
+		return 0, 0, 0, false
+	}
+
+	total = -1
+
+	if v[i+1:] != "*" {
+		var err error
+
+		if total, err = strconv.ParseInt(v[i+1:], 10, 64); err != nil {
+			return 0, 0, 0, false
+		}
+	}
+
+	if v[:i] == "*" {
+		return 0, 0, total, true
+	}
+
+	j := strings.IndexByte(v[:i], '-')
+
+	if j < 0 {
+		return 0, 0, 0, false
+	}
+
+	start, err1 := strconv.ParseInt(v[:j], 10, 64)
+	end, err2 := strconv.ParseInt(v[j+1:i], 10, 64)
+
+	if err1 != nil || err2 != nil || start > end {
+		return 0, 0, 0, false
+	}
+
+	return start, end, total, true
+}
+
+type checksum struct {
+	hash.Hash
+	expected []byte
+}
+
+type checksums []*checksum
+
+func (cs checksums) Write(p []byte) (int, error) {
+	for _, c := range cs {
+		c.Write(p)
+	}
+
+	return len(p), nil
+}
+
+func (cs checksums) verify() bool {
+	for _, c := range cs {
+		if !bytes.Equal(c.Sum(nil), c.expected) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// newChecksums returns the checksums which the downloaded file is verified
+// against. The "Content-MD5" header only covers the body of the response, so
+// it is ignored for partial responses.
+func newChecksums(sha256Sum []byte, res *Response) (checksums, error) {
+	var cs checksums
+
+	if sha256Sum != nil {
+		cs = append(cs, &checksum{Hash: sha256.New(), expected: sha256Sum})
+	}
+
+	for _, digest := range strings.Split(res.Header.Get("Digest"), ",") {
+		i := strings.IndexByte(digest, '=')
+
+		if i < 0 {
+			continue
+		}
+
+		var h hash.Hash
+
+		switch strings.ToLower(strings.TrimSpace(digest[:i])) {
+		case "sha-256":
+			h = sha256.New()
+		case "md5":
+			h = md5.New()
+		default:
+			continue
+		}
+
+		expected, err := base64.StdEncoding.DecodeString(strings.TrimSpace(digest[i+1:]))
+
+		if err != nil {
+			return nil, err
+		}
+
+		cs = append(cs, &checksum{Hash: h, expected: expected})
+	}
+
+	if v := res.Header.Get(headers.ContentMD5); v != "" && res.StatusCode == http.StatusOK {
+		expected, err := base64.StdEncoding.DecodeString(v)
+
+		if err != nil {
+			return nil, err
+		}
+
+		cs = append(cs, &checksum{Hash: md5.New(), expected: expected})
+	}
+
+	return cs, nil
+}
+
+func hashFile(path string, w io.Writer) error {
+	file, err := os.Open(path)
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	_, err = io.Copy(w, file)
+
+	return err
+}
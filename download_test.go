@@ -0,0 +1,206 @@
+package request
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-http-utils/headers"
+	"github.com/stretchr/testify/suite"
+)
+
+type DownloadSuite struct {
+	suite.Suite
+
+	content []byte
+	etag    string
+	ranges  []string
+	dir     string
+	path    string
+	server  *httptest.Server
+}
+
+func (s *DownloadSuite) SetupTest() {
+	s.content = []byte(strings.Repeat("0123456789", 10000))
+	s.etag = `"v1"`
+	s.ranges = nil
+	s.server = httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		s.ranges = append(s.ranges, req.Header.Get(headers.Range))
+
+		switch req.URL.Path {
+		case "/digest":
+			sum := sha256.Sum256(s.content)
+			res.Header().Set("Digest", "SHA-256="+base64.StdEncoding.EncodeToString(sum[:]))
+		case "/bad-digest":
+			res.Header().Set("Digest", "MD5="+base64.StdEncoding.EncodeToString(make([]byte, md5.Size)))
+		case "/content-md5":
+			sum := md5.Sum(s.content)
+			res.Header().Set(headers.ContentMD5, base64.StdEncoding.EncodeToString(sum[:]))
+		case "/missing":
+			http.NotFound(res, req)
+			return
+		case "/not-modified":
+			res.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		res.Header().Set(headers.ETag, s.etag)
+		http.ServeContent(res, req, "", time.Time{}, bytes.NewReader(s.content))
+	}))
+
+	dir, err := ioutil.TempDir("", "request")
+	s.Nil(err)
+
+	s.dir = dir
+	s.path = filepath.Join(dir, "file")
+}
+
+func (s *DownloadSuite) TearDownTest() {
+	s.server.Close()
+	os.RemoveAll(s.dir)
+}
+
+func (s *DownloadSuite) sha256() string {
+	sum := sha256.Sum256(s.content)
+
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *DownloadSuite) interrupted(n int, validator string) {
+	s.Nil(ioutil.WriteFile(s.path+partSuffix, s.content[:n], 0644))
+	s.Nil(ioutil.WriteFile(s.path+validatorSuffix, []byte(validator), 0644))
+}
+
+func (s *DownloadSuite) assertDownloaded() {
+	b, err := ioutil.ReadFile(s.path)
+
+	s.Nil(err)
+	s.Equal(s.content, b)
+
+	_, err = os.Stat(s.path + partSuffix)
+	s.True(os.IsNotExist(err))
+
+	_, err = os.Stat(s.path + validatorSuffix)
+	s.True(os.IsNotExist(err))
+}
+
+func (s *DownloadSuite) TestDownload() {
+	res, err := Get(s.server.URL).ExpectSHA256(s.sha256()).Download(s.path)
+
+	s.Nil(err)
+	s.Equal(http.StatusOK, res.StatusCode)
+	s.Equal([]string{""}, s.ranges)
+	s.assertDownloaded()
+}
+
+func (s *DownloadSuite) TestResume() {
+	s.interrupted(12345, s.etag)
+
+	res, err := Get(s.server.URL + "/digest").ExpectSHA256(s.sha256()).Download(s.path)
+
+	s.Nil(err)
+	s.Equal(http.StatusPartialContent, res.StatusCode)
+	s.Equal([]string{"bytes=12345-"}, s.ranges)
+	s.assertDownloaded()
+}
+
+func (s *DownloadSuite) TestResumeChanged() {
+	s.interrupted(12345, `"v0"`)
+
+	res, err := Get(s.server.URL).ExpectSHA256(s.sha256()).Download(s.path)
+
+	s.Nil(err)
+	s.Equal(http.StatusOK, res.StatusCode)
+	s.assertDownloaded()
+}
+
+func (s *DownloadSuite) TestResumeComplete() {
+	s.interrupted(len(s.content), s.etag)
+
+	_, err := Get(s.server.URL).ExpectSHA256(s.sha256()).Download(s.path)
+
+	s.Nil(err)
+	s.assertDownloaded()
+}
+
+func (s *DownloadSuite) TestResumeWithoutValidator() {
+	s.Nil(ioutil.WriteFile(s.path+partSuffix, []byte("stale"), 0644))
+
+	_, err := Get(s.server.URL).Download(s.path)
+
+	s.Nil(err)
+	s.Equal([]string{""}, s.ranges)
+	s.assertDownloaded()
+}
+
+func (s *DownloadSuite) TestDigest() {
+	_, err := Get(s.server.URL + "/digest").Download(s.path)
+
+	s.Nil(err)
+	s.assertDownloaded()
+
+	_, err = Get(s.server.URL + "/content-md5").Download(s.path)
+
+	s.Nil(err)
+	s.assertDownloaded()
+}
+
+func (s *DownloadSuite) TestChecksumMismatch() {
+	_, err := Get(s.server.URL + "/bad-digest").Download(s.path)
+
+	s.Equal(ErrChecksumMismatch, err)
+
+	_, err = Get(s.server.URL).ExpectSHA256(hex.EncodeToString(make([]byte, sha256.Size))).Download(s.path)
+
+	s.Equal(ErrChecksumMismatch, err)
+
+	for _, path := range []string{s.path, s.path + partSuffix, s.path + validatorSuffix} {
+		_, err = os.Stat(path)
+		s.True(os.IsNotExist(err))
+	}
+}
+
+func (s *DownloadSuite) TestInvalidChecksum() {
+	_, err := Get(s.server.URL).ExpectSHA256("xyz").Download(s.path)
+
+	s.NotNil(err)
+}
+
+func (s *DownloadSuite) TestNotOk() {
+	_, err := Get(s.server.URL + "/missing").Download(s.path)
+
+	s.Equal(http.StatusNotFound, err.(*StatusError).StatusCode)
+
+	_, err = os.Stat(s.path + partSuffix)
+	s.True(os.IsNotExist(err))
+}
+
+func (s *DownloadSuite) TestNotModified() {
+	s.Nil(ioutil.WriteFile(s.path, []byte("existing"), 0644))
+
+	_, err := Get(s.server.URL + "/not-modified").Download(s.path)
+
+	s.Equal(http.StatusNotModified, err.(*StatusError).StatusCode)
+	s.NotErrorIs(err, ErrStatusNotOk)
+
+	b, err := ioutil.ReadFile(s.path)
+	s.Nil(err)
+	s.Equal("existing", string(b))
+
+	_, err = os.Stat(s.path + partSuffix)
+	s.True(os.IsNotExist(err))
+}
+
+func TestDownload(t *testing.T) {
+	suite.Run(t, new(DownloadSuite))
+}
@@ -0,0 +1,57 @@
+package request
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// maxErrorBodySize is the max number of body bytes kept by a StatusError.
+const maxErrorBodySize = 1024
+
+// StatusError is the error returned when the response status code is not ok
+// (>= 400), or not expected such as a 304 response to Download.
+// errors.Is(err, ErrStatusNotOk) reports true for it only if the status code
+// is not ok.
+type StatusError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Header     http.Header
+	// Body is the response body, truncated to at most 1024 bytes.
+	Body []byte
+	// Value is the body decoded by Client.ErrorInto, if any.
+	Value interface{}
+}
+
+func newStatusError(r *Response, body []byte) *StatusError {
+	err := &StatusError{StatusCode: r.StatusCode, Header: r.Header}
+
+	if r.Request != nil {
+		err.Method = r.Request.Method
+		err.URL = r.Request.URL.String()
+	}
+
+	if len(body) > maxErrorBodySize {
+		body = body[:maxErrorBodySize]
+	}
+
+	err.Body = body
+
+	return err
+}
+
+func (e *StatusError) Error() string {
+	msg := fmt.Sprintf("request: %s %s: %d %s", e.Method, e.URL, e.StatusCode,
+		http.StatusText(e.StatusCode))
+
+	if len(e.Body) > 0 {
+		msg += ": " + string(e.Body)
+	}
+
+	return msg
+}
+
+// Is reports whether target is ErrStatusNotOk and the status code is not ok.
+func (e *StatusError) Is(target error) bool {
+	return target == ErrStatusNotOk && e.StatusCode >= 400
+}
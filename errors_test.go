@@ -0,0 +1,126 @@
+package request
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-http-utils/headers"
+	"github.com/stretchr/testify/suite"
+)
+
+type ErrorsSuite struct {
+	suite.Suite
+
+	server *httptest.Server
+}
+
+func (s *ErrorsSuite) SetupTest() {
+	s.server = httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/json":
+			res.Header().Set(headers.ContentType, "application/json")
+			res.WriteHeader(http.StatusConflict)
+			res.Write([]byte(`{"message":"conflict"}`))
+		case "/large":
+			res.WriteHeader(http.StatusBadGateway)
+			res.Write([]byte(strings.Repeat("a", 2*maxErrorBodySize)))
+		default:
+			res.Header().Set("X-Test-Key", "X-TEST-VALUE")
+			http.NotFound(res, req)
+		}
+	}))
+}
+
+func (s *ErrorsSuite) TearDownTest() {
+	s.server.Close()
+}
+
+func (s *ErrorsSuite) TestText() {
+	text, err := Get(s.server.URL + "/missing").Text()
+
+	s.Equal("404 page not found\n", text)
+	s.ErrorIs(err, ErrStatusNotOk)
+
+	statusErr, ok := err.(*StatusError)
+
+	s.True(ok)
+	s.Equal(http.MethodGet, statusErr.Method)
+	s.Equal(s.server.URL+"/missing", statusErr.URL)
+	s.Equal(http.StatusNotFound, statusErr.StatusCode)
+	s.Equal("X-TEST-VALUE", statusErr.Header.Get("X-Test-Key"))
+	s.Equal("404 page not found\n", string(statusErr.Body))
+	s.Contains(err.Error(), "404 Not Found")
+}
+
+func (s *ErrorsSuite) TestJSON() {
+	j, err := Get(s.server.URL + "/json").JSON()
+
+	s.Equal("conflict", GetPath(j, "message").(string))
+	s.ErrorIs(err, ErrStatusNotOk)
+	s.Equal(http.StatusConflict, err.(*StatusError).StatusCode)
+}
+
+func (s *ErrorsSuite) TestNotJSON() {
+	j, err := Get(s.server.URL + "/missing").JSON()
+
+	s.Nil(j)
+	s.Equal(http.StatusNotFound, err.(*StatusError).StatusCode)
+}
+
+type apiError struct {
+	Message string `json:"message"`
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("api: %s", e.Message)
+}
+
+func (s *ErrorsSuite) TestErrorInto() {
+	type result struct {
+		Message string `json:"message"`
+	}
+
+	success := &result{}
+	j, err := Get(s.server.URL + "/json").
+		ErrorInto(&apiError{}).
+		JSON(success)
+
+	s.Nil(j)
+	s.Empty(success.Message)
+	s.EqualError(err, "api: conflict")
+	s.Equal("conflict", err.(*apiError).Message)
+}
+
+func (s *ErrorsSuite) TestErrorIntoValue() {
+	var envelope map[string]string
+
+	_, err := Get(s.server.URL + "/json").
+		ErrorInto(&envelope).
+		JSON()
+
+	s.ErrorIs(err, ErrStatusNotOk)
+	s.Equal(&envelope, err.(*StatusError).Value)
+	s.Equal("conflict", envelope["message"])
+}
+
+func (s *ErrorsSuite) TestErrorIntoNotJSON() {
+	_, err := Get(s.server.URL + "/missing").
+		ErrorInto(&apiError{}).
+		JSON()
+
+	s.Equal(http.StatusNotFound, err.(*StatusError).StatusCode)
+	s.Nil(err.(*StatusError).Value)
+}
+
+func (s *ErrorsSuite) TestTruncateBody() {
+	_, err := Get(s.server.URL + "/large").Text()
+
+	s.Len(err.(*StatusError).Body, maxErrorBodySize)
+}
+
+func TestErrors(t *testing.T) {
+	suite.Run(t, new(ErrorsSuite))
+}
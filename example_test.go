@@ -1,8 +1,14 @@
 package request_test
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"net/textproto"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/DavidCai1993/request"
@@ -36,7 +42,7 @@ func ExampleGet() {
 		JSON()
 }
 
-func ExampleGetWithStruct() {
+func Example_getWithStruct() {
 	type MyResult struct {
 		Code  int                    `json:"code"`
 		Error string                 `json:"error"`
@@ -85,3 +91,177 @@ func ExampleClient_Attach() {
 		Attach("test.md", "./README.md", "README.md").
 		JSON()
 }
+
+func ExampleClient_Part() {
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", `form-data; name="metadata"`)
+	header.Set("Content-Type", "application/json")
+
+	json, err = request.
+		Post("http://mysite.com/photos").
+		Part(header, strings.NewReader(`{"title":"avatar"}`)).
+		AttachBytes("photo", "avatar.png", "image/png", []byte{}).
+		JSON()
+}
+
+func ExampleClient_BodyFunc() {
+	json, err = request.
+		Post("http://mysite.com/events").
+		BodyFunc(func(w io.Writer) error {
+			for i := 0; i < 3; i++ {
+				if _, err := fmt.Fprintf(w, "event %d\n", i); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}).
+		JSON()
+}
+
+func ExampleAgent() {
+	agent := request.NewAgent().
+		BaseURL("http://mysite.com/api/").
+		Set("X-HEADER-KEY", "foo").
+		Timeout(30 * time.Second)
+
+	json, err = agent.
+		Get("somebooks").
+		JSON()
+}
+
+func ExampleClient_WithContext() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	json, err = request.
+		Get("http://mysite.com/somebooks").
+		WithContext(ctx).
+		JSON()
+}
+
+func ExampleClient_Retry() {
+	policy := request.NewRetryPolicy(5)
+	policy.StatusCodes = append(policy.StatusCodes, http.StatusInternalServerError)
+
+	json, err = request.
+		Post("http://mysite.com").
+		Send(map[string]string{"name": "David"}).
+		Retry(policy).
+		JSON()
+}
+
+func ExampleClient_Use() {
+	logger := func(next request.Handler) request.Handler {
+		return func(req *http.Request) (*request.Response, error) {
+			start := time.Now()
+			res, err := next(req)
+			log.Printf("%s %s %v", req.Method, req.URL, time.Since(start))
+
+			return res, err
+		}
+	}
+
+	json, err = request.
+		Get("http://mysite.com/somebooks").
+		Use(logger).
+		JSON()
+}
+
+func ExampleClient_ErrorInto() {
+	type APIError struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+
+	apiErr := new(APIError)
+
+	json, err = request.
+		Get("http://mysite.com/somebooks").
+		ErrorInto(apiErr).
+		JSON()
+}
+
+func ExampleRegisterCodec() {
+	// yamlCodec implements request.Codec with a YAML library.
+	var yamlCodec request.Codec
+
+	request.RegisterCodec("application/yaml", yamlCodec)
+	request.RegisterType("yaml", "application/yaml")
+
+	var result map[string]interface{}
+
+	err = request.
+		Post("http://mysite.com").
+		Type("yaml").
+		Send(map[string]string{"name": "David"}).
+		Accept("yaml").
+		Decode(&result)
+}
+
+func ExampleClient_SendXML() {
+	type Book struct {
+		Title string `xml:"title"`
+	}
+
+	book := new(Book)
+
+	err = request.
+		Post("http://mysite.com/books").
+		SendXML(&Book{Title: "Go"}).
+		XML(book)
+}
+
+func ExampleClient_Download() {
+	res, err = request.
+		Get("http://mysite.com/archive.tar.gz").
+		ExpectSHA256("9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08").
+		Download("./archive.tar.gz")
+}
+
+func ExampleClient_Segments() {
+	res, err = request.
+		Get("http://mysite.com/archive.tar.gz").
+		Segments(4).
+		Download("./archive.tar.gz")
+}
+
+func ExampleTus() {
+	tus := request.NewTus(request.NewAgent(), "http://mysite.com/files/")
+	// Keeps the upload URLs in a file, so the uploads can be resumed after a
+	// restart.
+	tus.Store = request.NewFileTusStore("./uploads.json")
+	tus.Checksum = "sha1"
+
+	location, err := tus.UploadFile(context.Background(), "./video.mp4", map[string]string{
+		"type": "video/mp4",
+	})
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Println(location)
+}
+
+func ExampleLimiter() {
+	// The clients share 1 MB/s.
+	limiter := request.NewLimiter(1 << 20)
+
+	for _, name := range []string{"a.tar.gz", "b.tar.gz"} {
+		go request.
+			Get("http://mysite.com/backups/" + name).
+			Limiter(limiter).
+			Download("./" + name)
+	}
+}
+
+func ExampleClient_OnUploadProgress() {
+	json, err = request.
+		Post("http://mysite.com/upload").
+		Attach("file", "./archive.tar.gz", "archive.tar.gz").
+		OnUploadProgress(func(sent, total int64) {
+			log.Printf("%d / %d", sent, total)
+		}).
+		JSON()
+}
@@ -0,0 +1,32 @@
+package request
+
+// Do sends the HTTP request of c and decodes the response body into a value
+// of type T by Response.Decode, so the caller doesn't need to type-assert the
+// result of JSON:
+//
+//	user, err := request.Do[User](request.Get("http://mysite.com/users/1"))
+//
+// Errors are returned in the same way as Response.Decode, such as a
+// *StatusError or *Problem for non-ok responses, along with the value decoded
+// so far.
+func Do[T any](c *Client) (T, error) {
+	var v T
+
+	res, err := c.End()
+
+	if err != nil {
+		return v, err
+	}
+
+	return DecodeResponse[T](res)
+}
+
+// DecodeResponse decodes the response body into a value of type T by
+// Response.Decode, see Do for details.
+func DecodeResponse[T any](res *Response) (T, error) {
+	var v T
+
+	err := res.Decode(&v)
+
+	return v, err
+}
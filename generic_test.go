@@ -0,0 +1,79 @@
+package request
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-http-utils/headers"
+	"github.com/stretchr/testify/suite"
+)
+
+type GenericSuite struct {
+	suite.Suite
+
+	server *httptest.Server
+}
+
+type genericUser struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func (s *GenericSuite) SetupTest() {
+	s.server = httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/users":
+			res.Header().Set(headers.ContentType, "application/json")
+			res.Write([]byte(`[{"id":1,"name":"a"},{"id":2,"name":"b"}]`))
+		case "/users/1":
+			res.Header().Set(headers.ContentType, "application/json")
+			res.Write([]byte(`{"id":1,"name":"a"}`))
+		default:
+			res.Header().Set(headers.ContentType, problemJSON)
+			res.WriteHeader(http.StatusNotFound)
+			res.Write([]byte(`{"title":"user not found"}`))
+		}
+	}))
+}
+
+func (s *GenericSuite) TearDownTest() {
+	s.server.Close()
+}
+
+func (s *GenericSuite) TestDo() {
+	user, err := Do[genericUser](Get(s.server.URL + "/users/1"))
+
+	s.Nil(err)
+	s.Equal(genericUser{ID: 1, Name: "a"}, user)
+
+	users, err := Do[[]genericUser](Get(s.server.URL + "/users"))
+
+	s.Nil(err)
+	s.Equal([]genericUser{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}}, users)
+}
+
+func (s *GenericSuite) TestDoError() {
+	_, err := Do[genericUser](Get(s.server.URL + "/users/2"))
+
+	s.ErrorIs(err, ErrStatusNotOk)
+	s.Equal("user not found", err.(*Problem).Title)
+
+	_, err = Do[genericUser](Get(""))
+
+	s.NotNil(err)
+}
+
+func (s *GenericSuite) TestDecodeResponse() {
+	res, err := Get(s.server.URL + "/users/1").End()
+	s.Nil(err)
+
+	user, err := DecodeResponse[*genericUser](res)
+
+	s.Nil(err)
+	s.Equal(&genericUser{ID: 1, Name: "a"}, user)
+}
+
+func TestGeneric(t *testing.T) {
+	suite.Run(t, new(GenericSuite))
+}
@@ -0,0 +1,18 @@
+module github.com/DavidCai1993/request
+
+go 1.25.0
+
+require (
+	github.com/andybalholm/brotli v1.2.0
+	github.com/go-http-utils/headers v0.0.0-20181008091004-fed159eddc2a
+	github.com/klauspost/compress v1.18.0
+	github.com/stretchr/testify v1.8.4
+	golang.org/x/net v0.57.0
+	golang.org/x/text v0.40.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -0,0 +1,121 @@
+package request
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket which limits the bandwidth of the request and
+// response bodies in bytes per second. A Limiter is safe for concurrent use,
+// so it can be shared by many clients to cap them together.
+type Limiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  int
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter returns a new Limiter which allows bytesPerSecond bytes per
+// second, with bursts of at most bytesPerSecond bytes. It panics if
+// bytesPerSecond is not positive.
+func NewLimiter(bytesPerSecond int) *Limiter {
+	if bytesPerSecond <= 0 {
+		panic("request: non-positive rate limit")
+	}
+
+	return &Limiter{
+		rate:   float64(bytesPerSecond),
+		burst:  bytesPerSecond,
+		tokens: float64(bytesPerSecond),
+		last:   time.Now(),
+	}
+}
+
+// wait takes n bytes from the bucket, and waits until the bucket is no
+// longer in debt or ctx is done.
+func (l *Limiter) wait(ctx context.Context, n int) error {
+	l.mu.Lock()
+
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	l.last = now
+
+	if l.tokens > float64(l.burst) {
+		l.tokens = float64(l.burst)
+	}
+
+	l.tokens -= float64(n)
+	d := time.Duration(-l.tokens / l.rate * float64(time.Second))
+
+	l.mu.Unlock()
+
+	return sleep(ctx, d)
+}
+
+// RateLimit limits the bandwidth of the request body and the response body
+// of the request together to bytesPerSecond bytes per second. A non-positive
+// bytesPerSecond removes the limit.
+func (c *Client) RateLimit(bytesPerSecond int) *Client {
+	if bytesPerSecond <= 0 {
+		return c.Limiter(nil)
+	}
+
+	return c.Limiter(NewLimiter(bytesPerSecond))
+}
+
+// Limiter limits the bandwidth of the request body and the response body by
+// l, which may be shared by other clients.
+func (c *Client) Limiter(l *Limiter) *Client {
+	c.limiter = l
+
+	return c
+}
+
+// RateLimit limits the total bandwidth of all the requests spawned by the
+// agent to bytesPerSecond bytes per second. A non-positive bytesPerSecond
+// removes the limit.
+func (a *Agent) RateLimit(bytesPerSecond int) *Agent {
+	if bytesPerSecond <= 0 {
+		return a.Limiter(nil)
+	}
+
+	return a.Limiter(NewLimiter(bytesPerSecond))
+}
+
+// Limiter limits the bandwidth of all the requests spawned by the agent by l,
+// which may be shared by other agents and clients.
+func (a *Agent) Limiter(l *Limiter) *Agent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.limiter = l
+
+	return a
+}
+
+type limitedBody struct {
+	io.ReadCloser
+	ctx     context.Context
+	limiter *Limiter
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	// Reads at most a burst at once, so a large read does not take the bucket
+	// into a long debt.
+	if len(p) > b.limiter.burst {
+		p = p[:b.limiter.burst]
+	}
+
+	n, err := b.ReadCloser.Read(p)
+
+	if n > 0 {
+		if waitErr := b.limiter.wait(b.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+
+	return n, err
+}
@@ -0,0 +1,103 @@
+package request
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type LimitSuite struct {
+	suite.Suite
+
+	body   string
+	server *httptest.Server
+}
+
+func (s *LimitSuite) SetupTest() {
+	s.body = strings.Repeat("0123456789", 20000)
+	s.server = httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodPost {
+			b, _ := ioutil.ReadAll(req.Body)
+			res.Write([]byte(strings.Repeat("x", len(b)/1000)))
+			return
+		}
+
+		res.Write([]byte(s.body))
+	}))
+}
+
+func (s *LimitSuite) TearDownTest() {
+	s.server.Close()
+}
+
+func (s *LimitSuite) TestDownload() {
+	start := time.Now()
+	text, err := Get(s.server.URL).RateLimit(100000).Text()
+
+	// The first 100000 bytes are allowed by the burst.
+	s.Nil(err)
+	s.Equal(s.body, text)
+	s.True(time.Since(start) >= 900*time.Millisecond)
+	s.True(time.Since(start) < 3*time.Second)
+}
+
+func (s *LimitSuite) TestUpload() {
+	start := time.Now()
+	text, err := Post(s.server.URL).Send(s.body).RateLimit(100000).Text()
+
+	s.Nil(err)
+	s.Len(text, 200)
+	s.True(time.Since(start) >= 900*time.Millisecond)
+}
+
+func (s *LimitSuite) TestSharedLimiter() {
+	agent := NewAgent().RateLimit(200000)
+
+	var wg sync.WaitGroup
+	start := time.Now()
+
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			text, err := agent.Get(s.server.URL).Text()
+			s.Nil(err)
+			s.Equal(s.body, text)
+		}()
+	}
+
+	wg.Wait()
+
+	s.True(time.Since(start) >= 1900*time.Millisecond)
+}
+
+func (s *LimitSuite) TestContext() {
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	_, err := Get(s.server.URL).WithContext(ctx).RateLimit(10000).Text()
+
+	s.Equal(context.DeadlineExceeded, err)
+}
+
+func (s *LimitSuite) TestRemoveLimit() {
+	start := time.Now()
+	text, err := Get(s.server.URL).RateLimit(10).RateLimit(0).Text()
+
+	s.Nil(err)
+	s.Equal(s.body, text)
+	s.True(time.Since(start) < time.Second)
+}
+
+func TestLimit(t *testing.T) {
+	suite.Run(t, new(LimitSuite))
+}
@@ -0,0 +1,170 @@
+package request
+
+import (
+	"io"
+)
+
+// responseLimits limits the size of the response body, a zero value means no
+// limit.
+type responseLimits struct {
+	maxBytes        int64
+	maxDecompressed int64
+	maxRatio        float64
+}
+
+// MaxResponseBytes limits the response body to n bytes as received, reading
+// a larger body fails with ErrResponseTooLarge.
+func (c *Client) MaxResponseBytes(n int64) *Client {
+	c.limits.maxBytes = n
+
+	return c
+}
+
+// MaxDecompressedBytes limits the decompressed response body to n bytes,
+// decompressing a larger body fails with ErrResponseTooLarge.
+func (c *Client) MaxDecompressedBytes(n int64) *Client {
+	c.limits.maxDecompressed = n
+
+	return c
+}
+
+// MaxCompressionRatio limits the decompressed response body to ratio times
+// the compressed bytes read so far, which protects against decompression
+// bombs. Decompressing a body beyond the ratio fails with
+// ErrResponseTooLarge.
+func (c *Client) MaxCompressionRatio(ratio float64) *Client {
+	c.limits.maxRatio = ratio
+
+	return c
+}
+
+// MaxResponseBytes limits the response bodies of the requests spawned by the
+// agent, see Client.MaxResponseBytes.
+func (a *Agent) MaxResponseBytes(n int64) *Agent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.limits.maxBytes = n
+
+	return a
+}
+
+// MaxDecompressedBytes limits the decompressed response bodies of the
+// requests spawned by the agent, see Client.MaxDecompressedBytes.
+func (a *Agent) MaxDecompressedBytes(n int64) *Agent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.limits.maxDecompressed = n
+
+	return a
+}
+
+// MaxCompressionRatio limits the compression ratio of the response bodies of
+// the requests spawned by the agent, see Client.MaxCompressionRatio.
+func (a *Agent) MaxCompressionRatio(ratio float64) *Agent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.limits.maxRatio = ratio
+
+	return a
+}
+
+// limitRaw limits the raw response body read from body.
+func (r *Response) limitRaw(body io.Reader) (io.Reader, error) {
+	// The decompressed body is limited under the decompression already.
+	if r.limits.maxBytes <= 0 || r.decompressed {
+		return body, nil
+	}
+
+	if r.ContentLength > r.limits.maxBytes {
+		return nil, ErrResponseTooLarge
+	}
+
+	return &maxReader{r: body, n: r.limits.maxBytes}, nil
+}
+
+// decode returns the reader which decompresses raw and limits the
+// decompressed body, or nil if the body is not compressed.
+func (r *Response) decode(encoding string, raw io.Reader) (io.ReadCloser, error) {
+	compressed := &countReader{r: raw}
+	rc, err := decoder(encoding, compressed)
+
+	if err != nil || rc == nil {
+		return rc, err
+	}
+
+	var decompressed io.Reader = rc
+
+	if r.limits.maxDecompressed > 0 {
+		decompressed = &maxReader{r: decompressed, n: r.limits.maxDecompressed}
+	}
+
+	if r.limits.maxRatio > 0 {
+		decompressed = &ratioReader{r: decompressed, compressed: compressed, ratio: r.limits.maxRatio}
+	}
+
+	return &readCloser{Reader: decompressed, close: rc.Close}, nil
+}
+
+// maxReader reads at most n bytes from r, and fails with ErrResponseTooLarge
+// if there are more.
+type maxReader struct {
+	r io.Reader
+	n int64
+}
+
+func (m *maxReader) Read(p []byte) (int, error) {
+	if m.n <= 0 {
+		var b [1]byte
+		n, err := m.r.Read(b[:])
+
+		if n > 0 {
+			return 0, ErrResponseTooLarge
+		}
+
+		return 0, err
+	}
+
+	if int64(len(p)) > m.n {
+		p = p[:m.n]
+	}
+
+	n, err := m.r.Read(p)
+	m.n -= int64(n)
+
+	return n, err
+}
+
+type countReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+
+	return n, err
+}
+
+// ratioReader fails with ErrResponseTooLarge once the bytes read from r are
+// more than ratio times the bytes read from compressed.
+type ratioReader struct {
+	r          io.Reader
+	n          int64
+	compressed *countReader
+	ratio      float64
+}
+
+func (rr *ratioReader) Read(p []byte) (int, error) {
+	n, err := rr.r.Read(p)
+	rr.n += int64(n)
+
+	if float64(rr.n) > rr.ratio*float64(rr.compressed.n) {
+		return 0, ErrResponseTooLarge
+	}
+
+	return n, err
+}
@@ -0,0 +1,153 @@
+package request
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-http-utils/headers"
+	"github.com/stretchr/testify/suite"
+)
+
+type MaxBytesSuite struct {
+	suite.Suite
+
+	body   string
+	bomb   []byte
+	server *httptest.Server
+}
+
+func (s *MaxBytesSuite) SetupTest() {
+	s.body = strings.Repeat("request", 1000)
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write(make([]byte, 10<<20))
+	w.Close()
+	s.bomb = buf.Bytes()
+
+	s.server = httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/bomb":
+			res.Header().Set(headers.ContentEncoding, "gzip")
+			res.Write(s.bomb)
+		case "/chunked":
+			res.Write([]byte(s.body[:10]))
+			res.(http.Flusher).Flush()
+			res.Write([]byte(s.body[10:]))
+		default:
+			res.Header().Set(headers.ContentLength, strconv.Itoa(len(s.body)))
+			res.Write([]byte(s.body))
+		}
+	}))
+}
+
+func (s *MaxBytesSuite) TearDownTest() {
+	s.server.Close()
+}
+
+func (s *MaxBytesSuite) TestMaxResponseBytes() {
+	for _, path := range []string{"/", "/chunked"} {
+		_, err := Get(s.server.URL + path).MaxResponseBytes(100).Text()
+		s.Equal(ErrResponseTooLarge, err)
+
+		text, err := Get(s.server.URL + path).MaxResponseBytes(int64(len(s.body))).Text()
+		s.Nil(err)
+		s.Equal(s.body, text)
+	}
+}
+
+func (s *MaxBytesSuite) TestMaxResponseBytesStream() {
+	// The "Content-Length" is checked before reading the body.
+	res, err := Get(s.server.URL).MaxResponseBytes(100).End()
+	s.Nil(err)
+
+	_, err = res.Stream()
+	s.Equal(ErrResponseTooLarge, err)
+
+	res, err = Get(s.server.URL + "/chunked").MaxResponseBytes(100).End()
+	s.Nil(err)
+
+	stream, err := res.Stream()
+	s.Nil(err)
+
+	_, err = ioutil.ReadAll(stream)
+	s.Equal(ErrResponseTooLarge, err)
+	stream.Close()
+}
+
+func (s *MaxBytesSuite) TestMaxDecompressedBytes() {
+	res, err := Get(s.server.URL + "/bomb").MaxDecompressedBytes(1 << 20).End()
+	s.Nil(err)
+
+	_, err = res.Raw()
+	s.Equal(ErrResponseTooLarge, err)
+
+	res, err = Get(s.server.URL+"/bomb").
+		Set(headers.AcceptEncoding, "gzip").
+		MaxDecompressedBytes(1 << 20).
+		End()
+	s.Nil(err)
+
+	raw, err := res.Raw()
+	s.Nil(err)
+	s.Equal(s.bomb, raw)
+
+	_, err = res.Content()
+	s.Equal(ErrResponseTooLarge, err)
+
+	res, err = Get(s.server.URL + "/bomb").MaxDecompressedBytes(10 << 20).End()
+	s.Nil(err)
+
+	b, err := res.Content()
+	s.Nil(err)
+	s.Len(b, 10<<20)
+}
+
+func (s *MaxBytesSuite) TestMaxResponseBytesCompressed() {
+	// The body as received is limited, rather than the decompressed one.
+	res, err := Get(s.server.URL + "/bomb").MaxResponseBytes(int64(len(s.bomb))).End()
+	s.Nil(err)
+
+	b, err := ioutil.ReadAll(res.Body)
+	s.Nil(err)
+	s.Len(b, 10<<20)
+
+	_, err = Get(s.server.URL + "/bomb").MaxResponseBytes(int64(len(s.bomb)) - 1).Text()
+	s.Equal(ErrResponseTooLarge, err)
+}
+
+func (s *MaxBytesSuite) TestMaxCompressionRatio() {
+	_, err := Get(s.server.URL + "/bomb").MaxCompressionRatio(100).Text()
+	s.Equal(ErrResponseTooLarge, err)
+
+	res, err := Get(s.server.URL + "/bomb").MaxCompressionRatio(100).End()
+	s.Nil(err)
+
+	stream, err := res.Stream()
+	s.Nil(err)
+
+	_, err = ioutil.ReadAll(stream)
+	s.Equal(ErrResponseTooLarge, err)
+	stream.Close()
+
+	text, err := Get(s.server.URL).MaxCompressionRatio(1).Text()
+	s.Nil(err)
+	s.Equal(s.body, text)
+}
+
+func (s *MaxBytesSuite) TestAgent() {
+	agent := NewAgent().MaxResponseBytes(100)
+
+	_, err := agent.Get(s.server.URL).Text()
+	s.Equal(ErrResponseTooLarge, err)
+}
+
+func TestMaxBytes(t *testing.T) {
+	suite.Run(t, new(MaxBytesSuite))
+}
@@ -0,0 +1,53 @@
+package request
+
+import (
+	"net/http"
+)
+
+// Handler sends the HTTP request and returns the HTTP response.
+type Handler func(req *http.Request) (*Response, error)
+
+// Middleware wraps a Handler to add behavior around sending a request, such as
+// injecting headers, logging or refreshing credentials. It can inspect or
+// replace both the request passed to next and the response returned by it.
+type Middleware func(next Handler) Handler
+
+// Use appends middlewares to the request. The first middleware is the
+// outermost one, and the innermost handler sends the request, including all
+// of its retries.
+func (c *Client) Use(middlewares ...Middleware) *Client {
+	c.middlewares = append(c.middlewares, middlewares...)
+
+	return c
+}
+
+// Use appends middlewares to every spawned request, they run before the ones
+// added to the spawned Client itself.
+func (a *Agent) Use(middlewares ...Middleware) *Agent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.middlewares = append(a.middlewares, middlewares...)
+
+	return a
+}
+
+func (c *Client) handler() Handler {
+	h := Handler(c.send)
+
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		h = c.middlewares[i](h)
+	}
+
+	return h
+}
+
+func (c *Client) send(req *http.Request) (*Response, error) {
+	response, err := c.do(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Response{Response: response}, nil
+}
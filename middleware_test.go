@@ -0,0 +1,131 @@
+package request
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type MiddlewareSuite struct {
+	suite.Suite
+
+	server *httptest.Server
+}
+
+func (s *MiddlewareSuite) SetupTest() {
+	s.server = httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.Write([]byte(strings.Join(req.Header["X-Test-Key"], ",")))
+	}))
+}
+
+func (s *MiddlewareSuite) TearDownTest() {
+	s.server.Close()
+}
+
+func headerMiddleware(value string) Middleware {
+	return func(next Handler) Handler {
+		return func(req *http.Request) (*Response, error) {
+			req.Header.Add("X-Test-Key", value)
+			return next(req)
+		}
+	}
+}
+
+func (s *MiddlewareSuite) TestOrder() {
+	text, err := Get(s.server.URL).
+		Use(headerMiddleware("v1"), headerMiddleware("v2")).
+		Use(headerMiddleware("v3")).
+		Text()
+
+	s.Nil(err)
+	s.Equal("v1,v2,v3", text)
+
+	req, err := Get(s.server.URL).Use(headerMiddleware("v1"), headerMiddleware("v2")).Req()
+
+	s.Nil(err)
+	s.Empty(req.Header.Get("X-Test-Key"))
+}
+
+func (s *MiddlewareSuite) TestInspectResponse() {
+	var status int
+
+	_, err := Get(s.server.URL).
+		Use(func(next Handler) Handler {
+			return func(req *http.Request) (*Response, error) {
+				res, err := next(req)
+
+				if err == nil {
+					status = res.StatusCode
+				}
+
+				return res, err
+			}
+		}).
+		End()
+
+	s.Nil(err)
+	s.Equal(http.StatusOK, status)
+}
+
+func (s *MiddlewareSuite) TestReplaceResponse() {
+	text, err := Get(s.server.URL).
+		Use(func(next Handler) Handler {
+			return func(req *http.Request) (*Response, error) {
+				return &Response{Response: &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     make(http.Header),
+					Body:       ioutil.NopCloser(strings.NewReader("cached")),
+					Request:    req,
+				}}, nil
+			}
+		}).
+		Text()
+
+	s.Nil(err)
+	s.Equal("cached", text)
+}
+
+func (s *MiddlewareSuite) TestError() {
+	_, err := Get(s.server.URL).
+		Use(func(next Handler) Handler {
+			return func(req *http.Request) (*Response, error) {
+				return nil, errors.New("test")
+			}
+		}).
+		End()
+
+	s.EqualError(err, "test")
+}
+
+func (s *MiddlewareSuite) TestNoResponse() {
+	for _, res := range []*Response{nil, {}} {
+		_, err := Get(s.server.URL).
+			Use(func(next Handler) Handler {
+				return func(req *http.Request) (*Response, error) {
+					return res, nil
+				}
+			}).
+			End()
+
+		s.Equal(ErrNoResponse, err)
+	}
+}
+
+func (s *MiddlewareSuite) TestAgent() {
+	a := NewAgent().Use(headerMiddleware("agent"))
+
+	text, err := a.Get(s.server.URL).Use(headerMiddleware("client")).Text()
+
+	s.Nil(err)
+	s.Equal("agent,client", text)
+	s.Len(a.middlewares, 1)
+}
+
+func TestMiddleware(t *testing.T) {
+	suite.Run(t, new(MiddlewareSuite))
+}
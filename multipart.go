@@ -0,0 +1,276 @@
+package request
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// part is a part of the multipart body, its content is read from the reader
+// returned by open each time the body is sent.
+type part struct {
+	header textproto.MIMEHeader
+	// size is the size of the content, or -1 if it is unknown.
+	size int64
+	open func() (io.ReadCloser, error)
+	// once reports whether the content can only be read once.
+	once bool
+}
+
+// AttachReader adds the attachment read from r to the form, like Attach. If
+// contentType is empty, it is guessed from the extension of filename.
+//
+// r is read each time the request is sent, so the request can be retried or
+// redirected if r is a *bytes.Buffer, *bytes.Reader, *strings.Reader or an
+// io.Seeker, whose content is read from its current offset. Any other r, such
+// as a pipe or stdin, can only be sent once. r is never closed.
+func (c *Client) AttachReader(fieldname, filename, contentType string, r io.Reader) *Client {
+	return c.Part(formFileHeader(fieldname, filename, contentType), r)
+}
+
+// AttachBytes adds the attachment b to the form, like AttachReader.
+func (c *Client) AttachBytes(fieldname, filename, contentType string, b []byte) *Client {
+	return c.AttachReader(fieldname, filename, contentType, bytes.NewReader(b))
+}
+
+// Part adds a part with the header and the content read from r to the
+// multipart body, such as a JSON metadata part. r is read in the same way as
+// AttachReader.
+func (c *Client) Part(header textproto.MIMEHeader, r io.Reader) *Client {
+	if c.body != nil {
+		c.err = ErrBodyAlreadySet
+		return c
+	}
+
+	p, err := readerPart(header, r)
+
+	if err != nil {
+		c.err = err
+		return c
+	}
+
+	c.parts = append(c.parts, p)
+
+	return c
+}
+
+func readerPart(header textproto.MIMEHeader, r io.Reader) (*part, error) {
+	getBody, size, err := readerBody(r)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if getBody != nil {
+		return &part{header: header, size: size, open: getBody}, nil
+	}
+
+	var read bool
+
+	return &part{
+		header: header,
+		size:   -1,
+		once:   true,
+		open: func() (io.ReadCloser, error) {
+			if read {
+				return nil, errPartRead
+			}
+
+			read = true
+
+			return ioutil.NopCloser(r), nil
+		},
+	}, nil
+}
+
+var errPartRead = errors.New("request: the part can only be read once")
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// formFileHeader returns the header of the file part, the "Content-Type" is
+// guessed from the extension of filename if contentType is empty.
+func formFileHeader(fieldname, filename, contentType string) textproto.MIMEHeader {
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(filename))
+	}
+
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+		quoteEscaper.Replace(fieldname), quoteEscaper.Replace(filename)))
+	h.Set("Content-Type", contentType)
+
+	return h
+}
+
+// multipartBody returns the "Content-Type" of the multipart body, the function
+// which returns a new reader of it and the size of it which is -1 if the size
+// of any part is unknown. replayable reports whether the function can be
+// called more than once. The body is written to a pipe while it is read, so
+// the parts are never buffered in memory.
+func (c *Client) multipartBody() (contentType string, getBody func() (io.ReadCloser, error), size int64, replayable bool) {
+	parts := append([]*part(nil), c.parts...)
+	fields := make(url.Values, len(c.formVals))
+
+	for k, vs := range c.formVals {
+		fields[k] = append([]string(nil), vs...)
+	}
+
+	boundary := multipart.NewWriter(ioutil.Discard).Boundary()
+	replayable = true
+
+	for _, p := range parts {
+		if p.once {
+			replayable = false
+		}
+	}
+
+	getBody = func() (io.ReadCloser, error) {
+		pr, pw := io.Pipe()
+
+		return &pipeBody{pr: pr, pw: pw, write: func(w io.Writer) error {
+			return writeMultipart(w, boundary, parts, fields)
+		}}, nil
+	}
+
+	return "multipart/form-data; boundary=" + boundary, getBody,
+		multipartSize(boundary, parts, fields), replayable
+}
+
+// multipartSize returns the size of the multipart body, which is the size of
+// the body without the content of the parts plus the sizes of the contents.
+func multipartSize(boundary string, parts []*part, fields url.Values) int64 {
+	var cw countWriter
+	mw := multipart.NewWriter(&cw)
+	mw.SetBoundary(boundary)
+
+	size := int64(0)
+
+	for _, p := range parts {
+		if p.size < 0 {
+			return -1
+		}
+
+		mw.CreatePart(p.header)
+		size += p.size
+	}
+
+	writeFields(mw, fields)
+	mw.Close()
+
+	return size + int64(cw)
+}
+
+func writeMultipart(w io.Writer, boundary string, parts []*part, fields url.Values) error {
+	mw := multipart.NewWriter(w)
+
+	if err := mw.SetBoundary(boundary); err != nil {
+		return err
+	}
+
+	for _, p := range parts {
+		if err := writePart(mw, p); err != nil {
+			return err
+		}
+	}
+
+	if err := writeFields(mw, fields); err != nil {
+		return err
+	}
+
+	return mw.Close()
+}
+
+func writePart(mw *multipart.Writer, p *part) error {
+	pw, err := mw.CreatePart(p.header)
+
+	if err != nil {
+		return err
+	}
+
+	r, err := p.open()
+
+	if err != nil {
+		return err
+	}
+
+	defer r.Close()
+
+	n, err := io.Copy(pw, r)
+
+	if err != nil {
+		return err
+	}
+
+	// The "Content-Length" of the request was computed from the size.
+	if p.size >= 0 && n != p.size {
+		return fmt.Errorf("request: size of the part %q changed from %d to %d",
+			p.header.Get("Content-Disposition"), p.size, n)
+	}
+
+	return nil
+}
+
+// writeFields writes the form fields sorted by key.
+func writeFields(mw *multipart.Writer, fields url.Values) error {
+	keys := make([]string, 0, len(fields))
+
+	for k := range fields {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		for _, v := range fields[k] {
+			if err := mw.WriteField(k, v); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+type countWriter int64
+
+func (cw *countWriter) Write(p []byte) (int, error) {
+	*cw += countWriter(len(p))
+	return len(p), nil
+}
+
+// pipeBody is the reading half of a pipe, the writing goroutine is started on
+// the first Read, so nothing is leaked if the body is never read.
+type pipeBody struct {
+	once  sync.Once
+	pr    *io.PipeReader
+	pw    *io.PipeWriter
+	write func(w io.Writer) error
+}
+
+func (b *pipeBody) Read(p []byte) (int, error) {
+	b.once.Do(func() {
+		go func() {
+			b.pw.CloseWithError(b.write(b.pw))
+		}()
+	})
+
+	return b.pr.Read(p)
+}
+
+func (b *pipeBody) Close() error {
+	return b.pr.Close()
+}
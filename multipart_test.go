@@ -0,0 +1,224 @@
+package request
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type MultipartSuite struct {
+	suite.Suite
+
+	dir    string
+	server *httptest.Server
+}
+
+type multipartEcho struct {
+	ContentLength int64
+	Fields        map[string][]string
+	Files         map[string]string
+	Types         map[string]string
+}
+
+func (s *MultipartSuite) SetupTest() {
+	var err error
+	s.dir, err = ioutil.TempDir("", "request")
+	s.Nil(err)
+
+	s.server = httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if err := req.ParseMultipartForm(1 << 10); err != nil {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		echo := multipartEcho{
+			ContentLength: req.ContentLength,
+			Fields:        req.MultipartForm.Value,
+			Files:         make(map[string]string),
+			Types:         make(map[string]string),
+		}
+
+		for name, fhs := range req.MultipartForm.File {
+			file, _ := fhs[0].Open()
+			b, _ := ioutil.ReadAll(file)
+			file.Close()
+
+			echo.Files[name] = fhs[0].Filename + ":" + string(b)
+			echo.Types[name] = fhs[0].Header.Get("Content-Type")
+		}
+
+		res.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(res).Encode(echo)
+	}))
+}
+
+func (s *MultipartSuite) TearDownTest() {
+	s.server.Close()
+	os.RemoveAll(s.dir)
+}
+
+func (s *MultipartSuite) writeFile(name string, b []byte) string {
+	path := filepath.Join(s.dir, name)
+	s.Nil(ioutil.WriteFile(path, b, 0644))
+
+	return path
+}
+
+func (s *MultipartSuite) TestContentLength() {
+	path := s.writeFile("a.txt", []byte("hello"))
+
+	echo := new(multipartEcho)
+	_, err := Post(s.server.URL).
+		Field(url.Values{"k1": []string{"v1", "v2"}}).
+		Attach("file", path, `a "quoted".txt`).
+		JSON(echo)
+
+	s.Nil(err)
+	s.True(echo.ContentLength > 0)
+	s.Equal([]string{"v1", "v2"}, echo.Fields["k1"])
+	s.Equal(`a "quoted".txt:hello`, echo.Files["file"])
+}
+
+func (s *MultipartSuite) TestLargeFile() {
+	content := bytes.Repeat([]byte("request"), 1<<20)
+	path := s.writeFile("large.bin", content)
+
+	echo := new(multipartEcho)
+	_, err := Post(s.server.URL).Attach("file", path, "large.bin").JSON(echo)
+
+	s.Nil(err)
+	s.True(echo.ContentLength > int64(len(content)))
+	s.True(echo.Files["file"] == "large.bin:"+string(content))
+}
+
+func (s *MultipartSuite) TestFileChanged() {
+	path := s.writeFile("a.txt", []byte("hello"))
+
+	c := Post(s.server.URL).Attach("file", path, "a.txt")
+	s.Nil(ioutil.WriteFile(path, []byte("hello world"), 0644))
+
+	_, err := c.End()
+
+	s.NotNil(err)
+}
+
+func (s *MultipartSuite) TestContentType() {
+	path := s.writeFile("a.txt", []byte("hello"))
+
+	echo := new(multipartEcho)
+	_, err := Post(s.server.URL).
+		Attach("text", path, "a.txt").
+		Attach("binary", path, "a").
+		AttachBytes("image", "a.bin", "image/png", []byte("png")).
+		JSON(echo)
+
+	s.Nil(err)
+	s.Equal("text/plain; charset=utf-8", echo.Types["text"])
+	s.Equal("application/octet-stream", echo.Types["binary"])
+	s.Equal("image/png", echo.Types["image"])
+	s.Equal("a.bin:png", echo.Files["image"])
+}
+
+func (s *MultipartSuite) TestAttachReader() {
+	// A reader which is not a io.Seeker can only be sent once.
+	r := struct{ io.Reader }{strings.NewReader("hello")}
+
+	c := Post(s.server.URL).AttachReader("file", "a.json", "", r)
+	req, err := c.Req()
+
+	s.Nil(err)
+	s.Nil(req.GetBody)
+	s.Equal(int64(-1), req.ContentLength)
+
+	echo := new(multipartEcho)
+	_, err = c.JSON(echo)
+
+	s.Nil(err)
+	s.Equal("a.json:hello", echo.Files["file"])
+	s.Equal("application/json", echo.Types["file"])
+}
+
+func (s *MultipartSuite) TestAttachPipe() {
+	pr, pw, err := os.Pipe()
+	s.Nil(err)
+	defer pr.Close()
+
+	go func() {
+		pw.Write([]byte("hello"))
+		pw.Close()
+	}()
+
+	c := Post(s.server.URL).AttachReader("file", "a.txt", "", pr)
+	req, err := c.Req()
+
+	s.Nil(err)
+	s.Nil(req.GetBody)
+
+	echo := new(multipartEcho)
+	_, err = c.JSON(echo)
+
+	s.Nil(err)
+	s.Equal("a.txt:hello", echo.Files["file"])
+}
+
+func (s *MultipartSuite) TestAttachSeeker() {
+	path := s.writeFile("a.txt", []byte("skip:hello"))
+	file, err := os.Open(path)
+	s.Nil(err)
+	defer file.Close()
+
+	file.Seek(5, io.SeekStart)
+
+	var attempts int32
+	server := failingServer(1, http.StatusBadGateway, &attempts)
+	defer server.Close()
+
+	text, err := Post(server.URL).
+		AttachReader("file", "a.txt", "", file).
+		Retry(NewRetryPolicy(2)).
+		Text()
+
+	s.Nil(err)
+	s.Equal(int32(2), attempts)
+	s.Contains(text, "\r\n\r\nhello\r\n--")
+}
+
+func (s *MultipartSuite) TestPart() {
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", `form-data; name="metadata"`)
+	header.Set("Content-Type", "application/json")
+
+	echo := new(multipartEcho)
+	_, err := Post(s.server.URL).
+		Part(header, strings.NewReader(`{"name":"a"}`)).
+		AttachBytes("file", "a.txt", "", []byte("hello")).
+		JSON(echo)
+
+	s.Nil(err)
+	s.Equal([]string{`{"name":"a"}`}, echo.Fields["metadata"])
+	s.Equal("a.txt:hello", echo.Files["file"])
+}
+
+func (s *MultipartSuite) TestPartAfterSend() {
+	_, err := Post(s.server.URL).
+		Send("hello").
+		AttachBytes("file", "a.txt", "", []byte("hello")).
+		End()
+
+	s.Equal(ErrBodyAlreadySet, err)
+}
+
+func TestMultipart(t *testing.T) {
+	suite.Run(t, new(MultipartSuite))
+}
@@ -0,0 +1,124 @@
+package request
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+const problemJSON = "application/problem+json"
+
+// Problem is a problem details object defined by RFC 7807 and RFC 9457. It is
+// returned as the error by JSON when a non-ok response has the
+// "application/problem+json" content type.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title,omitempty"`
+	Status   int    `json:"status,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// Extensions holds the extension members of the problem details object.
+	Extensions map[string]interface{} `json:"-"`
+}
+
+var problemMembers = map[string]bool{
+	"type": true, "title": true, "status": true, "detail": true, "instance": true,
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Members with an invalid type are
+// ignored as required by the RFC, and unknown members are kept in Extensions.
+func (p *Problem) UnmarshalJSON(b []byte) error {
+	var members map[string]json.RawMessage
+
+	if err := json.Unmarshal(b, &members); err != nil {
+		return err
+	}
+
+	json.Unmarshal(members["type"], &p.Type)
+	json.Unmarshal(members["title"], &p.Title)
+	json.Unmarshal(members["status"], &p.Status)
+	json.Unmarshal(members["detail"], &p.Detail)
+	json.Unmarshal(members["instance"], &p.Instance)
+
+	for k, raw := range members {
+		if problemMembers[k] {
+			continue
+		}
+
+		var v interface{}
+
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return err
+		}
+
+		if p.Extensions == nil {
+			p.Extensions = make(map[string]interface{})
+		}
+
+		p.Extensions[k] = v
+	}
+
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, the extension members are inlined.
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	members := make(map[string]interface{}, len(p.Extensions)+5)
+
+	for k, v := range p.Extensions {
+		members[k] = v
+	}
+
+	type problem Problem
+	b, err := json.Marshal((*problem)(p))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(b, &members); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(members)
+}
+
+func (p *Problem) Error() string {
+	msg := fmt.Sprintf("request: problem %s: %d %s", p.Type, p.Status, p.Title)
+
+	if p.Detail != "" {
+		msg += ": " + p.Detail
+	}
+
+	return msg
+}
+
+// Is reports whether target is ErrStatusNotOk and the status of the problem
+// is not ok.
+func (p *Problem) Is(target error) bool {
+	return target == ErrStatusNotOk && p.Status >= 400
+}
+
+func (r *Response) problem(b []byte) error {
+	p := new(Problem)
+
+	if err := json.Unmarshal(b, p); err != nil {
+		return newStatusError(r, b)
+	}
+
+	// "about:blank" is the default type, and the status of the response is
+	// used when the status member is absent.
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+
+	if p.Status == 0 {
+		p.Status = r.StatusCode
+	}
+
+	if p.Title == "" && p.Type == "about:blank" {
+		p.Title = http.StatusText(p.Status)
+	}
+
+	return p
+}
@@ -0,0 +1,122 @@
+package request
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-http-utils/headers"
+	"github.com/stretchr/testify/suite"
+)
+
+type ProblemSuite struct {
+	suite.Suite
+
+	server *httptest.Server
+}
+
+func (s *ProblemSuite) SetupTest() {
+	s.server = httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.Header().Set(headers.ContentType, "application/problem+json; charset=utf-8")
+
+		switch req.URL.Path {
+		case "/blank":
+			res.WriteHeader(http.StatusNotFound)
+			res.Write([]byte(`{}`))
+		case "/ok":
+			res.Write([]byte(`{"title": "x"}`))
+		case "/invalid":
+			res.WriteHeader(http.StatusBadRequest)
+			res.Write([]byte(`not json`))
+		default:
+			res.WriteHeader(http.StatusForbidden)
+			res.Write([]byte(`{
+				"type": "https://example.com/probs/out-of-credit",
+				"title": "You do not have enough credit.",
+				"status": 403,
+				"detail": "Your current balance is 30, but that costs 50.",
+				"instance": "/account/12345/msgs/abc",
+				"balance": 30
+			}`))
+		}
+	}))
+}
+
+func (s *ProblemSuite) TearDownTest() {
+	s.server.Close()
+}
+
+func (s *ProblemSuite) TestProblem() {
+	j, err := Get(s.server.URL).JSON()
+
+	s.Nil(j)
+	s.ErrorIs(err, ErrStatusNotOk)
+
+	p, ok := err.(*Problem)
+
+	s.True(ok)
+	s.Equal("https://example.com/probs/out-of-credit", p.Type)
+	s.Equal("You do not have enough credit.", p.Title)
+	s.Equal(http.StatusForbidden, p.Status)
+	s.Equal("Your current balance is 30, but that costs 50.", p.Detail)
+	s.Equal("/account/12345/msgs/abc", p.Instance)
+	s.Equal(float64(30), p.Extensions["balance"])
+	s.Contains(err.Error(), "You do not have enough credit.")
+}
+
+func (s *ProblemSuite) TestDefaults() {
+	_, err := Get(s.server.URL + "/blank").JSON()
+
+	p := err.(*Problem)
+
+	s.Equal("about:blank", p.Type)
+	s.Equal(http.StatusNotFound, p.Status)
+	s.Equal("Not Found", p.Title)
+	s.Nil(p.Extensions)
+}
+
+func (s *ProblemSuite) TestInvalid() {
+	_, err := Get(s.server.URL + "/invalid").JSON()
+
+	s.Equal(http.StatusBadRequest, err.(*StatusError).StatusCode)
+}
+
+func (s *ProblemSuite) TestOK() {
+	// A problem details body of an ok response is decoded as usual.
+	var v struct{ Title string }
+
+	_, err := Get(s.server.URL + "/ok").JSON(&v)
+
+	s.Nil(err)
+	s.Equal("x", v.Title)
+
+	v.Title = ""
+
+	s.Nil(Get(s.server.URL + "/ok").Decode(&v))
+	s.Equal("x", v.Title)
+}
+
+func (s *ProblemSuite) TestErrorInto() {
+	_, err := Get(s.server.URL).ErrorInto(&apiError{}).JSON()
+
+	_, ok := err.(*apiError)
+	s.True(ok)
+}
+
+func (s *ProblemSuite) TestMarshal() {
+	p := &Problem{Type: "about:blank", Status: 404, Extensions: map[string]interface{}{"k1": "v1"}}
+	b, err := json.Marshal(p)
+
+	s.Nil(err)
+	s.JSONEq(`{"type":"about:blank","status":404,"k1":"v1"}`, string(b))
+
+	decoded := new(Problem)
+
+	s.Nil(json.Unmarshal(b, decoded))
+	s.Equal(p, decoded)
+}
+
+func TestProblem(t *testing.T) {
+	suite.Run(t, new(ProblemSuite))
+}
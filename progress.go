@@ -0,0 +1,66 @@
+package request
+
+import (
+	"io"
+	"sync"
+)
+
+// OnUploadProgress sets the function which is called with the number of
+// bytes of the request body sent so far, and the size of the body which is -1
+// if unknown. The count starts from zero again if the request is retried or
+// redirected.
+func (c *Client) OnUploadProgress(fn func(sent, total int64)) *Client {
+	c.uploadProgress = fn
+
+	return c
+}
+
+// OnDownloadProgress sets the function which is called with the number of
+// bytes of the response body received so far, and the "Content-Length" of the
+// response which is -1 if unknown, while the body is read by Raw, Stream,
+// WriteTo, SaveTo or Download. The bytes are counted before they are
+// decompressed.
+//
+// If Segments is used, the progress of the whole file is reported, and fn is
+// never called concurrently.
+func (c *Client) OnDownloadProgress(fn func(received, total int64)) *Client {
+	c.downloadProgress = fn
+
+	return c
+}
+
+// progress reports the number of transferred bytes to fn.
+type progress struct {
+	mu    sync.Mutex
+	n     int64
+	total int64
+	fn    func(n, total int64)
+}
+
+func (p *progress) add(n int) {
+	if p == nil || n == 0 {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.n += int64(n)
+	p.fn(p.n, p.total)
+}
+
+type progressBody struct {
+	io.ReadCloser
+	progress *progress
+}
+
+func newProgressBody(body io.ReadCloser, total int64, fn func(n, total int64)) io.ReadCloser {
+	return &progressBody{ReadCloser: body, progress: &progress{total: total, fn: fn}}
+}
+
+func (b *progressBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.progress.add(n)
+
+	return n, err
+}
@@ -0,0 +1,113 @@
+package request
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-http-utils/headers"
+	"github.com/stretchr/testify/suite"
+)
+
+type ProgressSuite struct {
+	suite.Suite
+
+	body   string
+	server *httptest.Server
+}
+
+func (s *ProgressSuite) SetupTest() {
+	s.body = strings.Repeat("request", 10000)
+	s.server = httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		b, _ := ioutil.ReadAll(req.Body)
+
+		if req.URL.Path == "/chunked" {
+			res.Write(b)
+			res.(http.Flusher).Flush()
+			return
+		}
+
+		res.Header().Set(headers.ContentLength, strconv.Itoa(len(s.body)))
+		res.Write([]byte(s.body))
+	}))
+}
+
+func (s *ProgressSuite) TearDownTest() {
+	s.server.Close()
+}
+
+func (s *ProgressSuite) TestUploadProgress() {
+	var sent, total int64
+
+	// The body is sent by another goroutine of the transport.
+	_, err := Post(s.server.URL).
+		Send(s.body).
+		OnUploadProgress(func(n, t int64) {
+			s.True(n > atomic.LoadInt64(&sent))
+			atomic.StoreInt64(&sent, n)
+			atomic.StoreInt64(&total, t)
+		}).
+		End()
+
+	s.Nil(err)
+	s.Equal(int64(len(s.body)), atomic.LoadInt64(&sent))
+	s.Equal(int64(len(s.body)), atomic.LoadInt64(&total))
+}
+
+func (s *ProgressSuite) TestUploadProgressRetry() {
+	var attempts, sent int32
+	server := failingServer(1, http.StatusBadGateway, &attempts)
+	defer server.Close()
+
+	text, err := Post(server.URL).
+		Send(s.body).
+		Retry(NewRetryPolicy(2)).
+		OnUploadProgress(func(n, t int64) {
+			if n == t {
+				atomic.AddInt32(&sent, 1)
+			}
+		}).
+		Text()
+
+	s.Nil(err)
+	s.Equal(s.body, text)
+	s.Equal(int32(2), atomic.LoadInt32(&sent))
+}
+
+func (s *ProgressSuite) TestDownloadProgress() {
+	var received, total int64
+
+	text, err := Get(s.server.URL).
+		OnDownloadProgress(func(n, t int64) {
+			s.True(n > received)
+			received, total = n, t
+		}).
+		Text()
+
+	s.Nil(err)
+	s.Equal(s.body, text)
+	s.Equal(int64(len(s.body)), received)
+	s.Equal(int64(len(s.body)), total)
+}
+
+func (s *ProgressSuite) TestUnknownTotal() {
+	var total int64
+
+	_, err := Post(s.server.URL + "/chunked").
+		Send(s.body).
+		OnDownloadProgress(func(n, t int64) {
+			total = t
+		}).
+		Text()
+
+	s.Nil(err)
+	s.Equal(int64(-1), total)
+}
+
+func TestProgress(t *testing.T) {
+	suite.Run(t, new(ProgressSuite))
+}
@@ -2,16 +2,16 @@ package request
 
 import (
 	"bytes"
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
 	"io"
-	"mime/multipart"
 	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-http-utils/headers"
@@ -23,11 +23,17 @@ const Version = "1.6.0"
 
 // Errors used by this package.
 var (
-	ErrNotPOST        = errors.New("request: method is not POST when using form")
-	ErrLackURL        = errors.New("request: request lacks URL")
-	ErrLackMethod     = errors.New("request: request lacks method")
-	ErrBodyAlreadySet = errors.New("request: request body has already been set")
-	ErrStatusNotOk    = errors.New("request: status code is not ok (>= 400)")
+	ErrNotPOST           = errors.New("request: method is not POST when using form")
+	ErrLackURL           = errors.New("request: request lacks URL")
+	ErrLackMethod        = errors.New("request: request lacks method")
+	ErrBodyAlreadySet    = errors.New("request: request body has already been set")
+	ErrStatusNotOk       = errors.New("request: status code is not ok (>= 400)")
+	ErrNoCodec           = errors.New("request: no codec is registered for the content type")
+	ErrBodyStreamed      = errors.New("request: response body has already been streamed")
+	ErrChecksumMismatch  = errors.New("request: checksum of the downloaded file mismatches")
+	ErrTusOffsetMismatch = errors.New("request: tus upload offset mismatch")
+	ErrResponseTooLarge  = errors.New("request: response body is too large")
+	ErrNoResponse        = errors.New("request: middleware returned no response")
 )
 
 type maxRedirects int
@@ -46,22 +52,39 @@ type basicAuthInfo struct {
 
 // Client is a HTTP client which provides usable and chainable methods.
 type Client struct {
-	cli       *http.Client
-	req       *http.Request
-	res       *Response
-	method    string
-	url       *url.URL
-	queryVals url.Values
-	formVals  url.Values
-	mw        *multipart.Writer
-	mwBuf     *bytes.Buffer
-	body      io.Reader
-	basicAuth *basicAuthInfo
-	header    http.Header
-	cookies   []*http.Cookie
-	timeout   time.Duration
-	redirects maxRedirects
-	err       error
+	cli               *http.Client
+	req               *http.Request
+	res               *Response
+	ctx               context.Context
+	method            string
+	base              *url.URL
+	url               *url.URL
+	queryVals         url.Values
+	formVals          url.Values
+	parts             []*part
+	body              io.Reader
+	basicAuth         *basicAuthInfo
+	header            http.Header
+	cookies           []*http.Cookie
+	timeout           time.Duration
+	redirects         maxRedirects
+	retry             *RetryPolicy
+	middlewares       []Middleware
+	errInto           interface{}
+	lenientJSON       bool
+	sha256            []byte
+	uploadProgress    func(sent, total int64)
+	downloadProgress  func(received, total int64)
+	limiter           *Limiter
+	compression       string
+	compressThreshold int64
+	limits            responseLimits
+	charset           string
+	noTranscode       bool
+	jsonOptions       jsonOptions
+	decompress        bool
+	segments          int
+	err               error
 }
 
 // New returns a new instance of Client.
@@ -71,14 +94,47 @@ func New() *Client {
 		header:   make(http.Header),
 		formVals: make(url.Values),
 		cookies:  make([]*http.Cookie, 0),
-		mwBuf:    bytes.NewBuffer(nil),
 	}
-	c.mw = multipart.NewWriter(c.mwBuf)
 
 	return c
 }
 
-// To defines the method and URL of the request.
+// clone returns a new Client which has the configuration of c, but neither
+// its body nor its result, to send another request on behalf of c.
+func (c *Client) clone() *Client {
+	n := New()
+	cli := *c.cli
+	n.cli = &cli
+	n.ctx = c.ctx
+	n.method = c.method
+	n.base = c.base
+	n.basicAuth = c.basicAuth
+	n.retry = c.retry
+	n.limiter = c.limiter
+	n.limits = c.limits
+	n.err = c.err
+	n.cookies = append(n.cookies, c.cookies...)
+	n.middlewares = append(n.middlewares, c.middlewares...)
+
+	if c.url != nil {
+		u := *c.url
+		n.url = &u
+		n.queryVals = make(url.Values, len(c.queryVals))
+
+		for k, v := range c.queryVals {
+			n.queryVals[k] = append([]string(nil), v...)
+		}
+	}
+
+	for k, v := range c.header {
+		n.header[k] = append([]string(nil), v...)
+	}
+
+	return n
+}
+
+// To defines the method and URL of the request. If the client was spawned by
+// an Agent with a base URL, a relative URL is resolved against it.
 func (c *Client) To(method string, URL string) *Client {
 	c.method = method
 	u, err := url.Parse(URL)
@@ -88,6 +144,10 @@ func (c *Client) To(method string, URL string) *Client {
 		return c
 	}
 
+	if c.base != nil {
+		u = c.base.ResolveReference(u)
+	}
+
 	c.url = u
 	c.queryVals = u.Query()
 
@@ -172,6 +232,28 @@ var typesMap = map[string]string{
 	"multipart":  "multipart/form-data",
 }
 
+var typesMu sync.RWMutex
+
+// RegisterType registers the shorthand of the media type which can be used
+// by Type and Accept, such as RegisterType("yaml", "application/yaml").
+func RegisterType(shorthand, mediaType string) {
+	typesMu.Lock()
+	defer typesMu.Unlock()
+
+	typesMap[strings.TrimSpace(strings.ToLower(shorthand))] = mediaType
+}
+
+func lookupType(t string) string {
+	typesMu.RLock()
+	defer typesMu.RUnlock()
+
+	if typ, ok := typesMap[strings.TrimSpace(strings.ToLower(t))]; ok {
+		return typ
+	}
+
+	return t
+}
+
 // Type sets the "Content-Type" request header to the given value.
 // Some shorthands are supported:
 //
@@ -185,13 +267,9 @@ var typesMap = map[string]string{
 // "multipart":  "multipart/form-data"
 //
 // So you can just call .Type("html") to set the "Content-Type"
-// header to "text/html".
+// header to "text/html". More shorthands can be added by RegisterType.
 func (c *Client) Type(t string) *Client {
-	if typ, ok := typesMap[strings.TrimSpace(strings.ToLower(t))]; ok {
-		return c.Set(headers.ContentType, typ)
-	}
-
-	return c.Set(headers.ContentType, t)
+	return c.Set(headers.ContentType, lookupType(t))
 }
 
 // Accept sets the "Accept" request header to the given value.
@@ -207,13 +285,9 @@ func (c *Client) Type(t string) *Client {
 // "multipart":  "multipart/form-data"
 //
 // So you can just call .Accept("json") to set the "Accept"
-// header to "application/json".
+// header to "application/json". More shorthands can be added by RegisterType.
 func (c *Client) Accept(t string) *Client {
-	if typ, ok := typesMap[strings.TrimSpace(strings.ToLower(t))]; ok {
-		return c.Set(headers.Accept, typ)
-	}
-
-	return c.Set(headers.Accept, t)
+	return c.Set(headers.Accept, lookupType(t))
 }
 
 // Query adds the the given value to request's URL query-string.
@@ -227,29 +301,38 @@ func (c *Client) Query(vals url.Values) *Client {
 	return c
 }
 
-// Send sends the body in JSON format, body can be anything which can be
-// Marshaled or just Marshaled JSON string.
+// Send sends the body marshaled by the codec registered for the current
+// "Content-Type" of the request, so Type should be called before Send. If no
+// codec is registered for it, the body is sent in JSON format and the
+// "Content-Type" is set to "application/json". Body can be anything which can
+// be Marshaled or just Marshaled string.
 func (c *Client) Send(body interface{}) *Client {
-	if c.body != nil || c.mwBuf.Len() != 0 {
+	if c.body != nil || len(c.parts) != 0 {
 		c.err = ErrBodyAlreadySet
 		return c
 	}
 
+	codec, ok := codecFor(mediaType(c.header.Get(headers.ContentType)))
+
+	if !ok {
+		codec = jsonCodec{}
+		c.Set(headers.ContentType, "application/json")
+	}
+
 	switch body := body.(type) {
 	case string:
 		c.body = bytes.NewBufferString(body)
 	default:
-		j, err := json.Marshal(body)
+		b, err := codec.Marshal(body)
 
 		if err != nil {
 			c.err = err
 			return c
 		}
 
-		c.body = bytes.NewReader(j)
+		c.body = bytes.NewReader(b)
 	}
 
-	c.Set(headers.ContentType, "application/json")
 	return c
 }
 
@@ -316,72 +399,102 @@ func (c *Client) Field(vals url.Values) *Client {
 
 // Attach adds the attachment file to the form. Once the attachment was
 // set, the "Content-Type" will be set to "multipart/form-data; boundary=xxx"
-// automatically.
+// automatically. The file is not read until the request is sent, and then it
+// is streamed instead of being buffered in memory. The "Content-Type" of the
+// part is guessed from the extension of filename, and is
+// "application/octet-stream" if unknown.
 func (c *Client) Attach(fieldname, path, filename string) *Client {
 	if c.body != nil {
 		c.err = ErrBodyAlreadySet
 		return c
 	}
 
-	file, err := os.Open(path)
+	info, err := os.Stat(path)
 
 	if err != nil {
 		c.err = err
 		return c
 	}
 
-	fw, err := c.mw.CreateFormFile(fieldname, filename)
+	c.parts = append(c.parts, &part{
+		header: formFileHeader(fieldname, filename, ""),
+		size:   info.Size(),
+		open: func() (io.ReadCloser, error) {
+			return os.Open(path)
+		},
+	})
+
+	return c
+}
+
+// Proxy sets the address of the proxy which used by the request.
+func (c *Client) Proxy(addr string) *Client {
+	transport, err := proxyTransport(addr)
 
 	if err != nil {
 		c.err = err
 		return c
 	}
 
-	if _, err = io.Copy(fw, file); err != nil {
-		c.err = err
-		return c
+	if transport != nil {
+		c.cli.Transport = transport
 	}
 
 	return c
 }
 
-// Proxy sets the address of the proxy which used by the request.
-func (c *Client) Proxy(addr string) *Client {
+func proxyTransport(addr string) (*http.Transport, error) {
 	u, err := url.Parse(addr)
 
 	if err != nil {
-		c.err = err
-		return c
+		return nil, err
 	}
 
 	switch u.Scheme {
 	case "http", "https":
-		c.cli.Transport = &http.Transport{
+		return &http.Transport{
 			Proxy: http.ProxyURL(u),
 			DialContext: (&net.Dialer{
 				Timeout:   30 * time.Second,
 				KeepAlive: 30 * time.Second,
 			}).DialContext,
 			TLSHandshakeTimeout: 10 * time.Second,
-		}
+		}, nil
 	case "socks5":
 		dialer, err := proxy.FromURL(u, proxy.Direct)
 
 		if err != nil {
-			c.err = err
-			return c
+			return nil, err
 		}
 
-		c.cli.Transport = &http.Transport{
+		return &http.Transport{
 			Proxy:               http.ProxyFromEnvironment,
 			Dial:                dialer.Dial,
 			TLSHandshakeTimeout: 10 * time.Second,
-		}
+		}, nil
 	}
 
+	return nil, nil
+}
+
+// WithContext binds the request to ctx, so its cancellation and deadline
+// apply to sending the request and reading the response body, and its values
+// are visible to the transport.
+func (c *Client) WithContext(ctx context.Context) *Client {
+	if ctx == nil {
+		panic("request: nil context")
+	}
+
+	c.ctx = ctx
+
 	return c
 }
 
+// EndContext equals WithContext(ctx).End() .
+func (c *Client) EndContext(ctx context.Context) (*Response, error) {
+	return c.WithContext(ctx).End()
+}
+
 // End sends the HTTP request and returns the HTTP reponse.
 //
 // An error is returned if caused by client policy (such as timeout), or
@@ -405,18 +518,48 @@ func (c *Client) End() (*Response, error) {
 		return nil, err
 	}
 
-	response, err := c.cli.Do(c.req)
+	res, err := c.handler()(c.req)
+
+	if err == nil && (res == nil || res.Response == nil) {
+		err = ErrNoResponse
+	}
 
 	if err != nil {
 		c.err = err
 		return nil, err
 	}
 
-	c.res = &Response{Response: response}
+	if c.downloadProgress != nil {
+		res.Body = newProgressBody(res.Body, res.ContentLength, c.downloadProgress)
+	}
+
+	if c.limiter != nil {
+		res.Body = &limitedBody{ReadCloser: res.Body, ctx: c.req.Context(), limiter: c.limiter}
+	}
+
+	c.res = res
+	c.res.errInto = c.errInto
+	c.res.lenient = c.lenientJSON
+	c.res.limits = c.limits
+	c.res.charset = c.charset
+	c.res.noTranscode = c.noTranscode
+	c.res.jsonOptions = c.jsonOptions
+
+	if c.decompress {
+		c.res.decompress()
+	}
 
 	return c.res, nil
 }
 
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if c.retry == nil {
+		return c.cli.Do(req)
+	}
+
+	return c.retry.do(c.cli, req)
+}
+
 // Req returns the representing http.Request instance of this request.
 // It is often used in wirting tests.
 func (c *Client) Req() (*http.Request, error) {
@@ -449,6 +592,50 @@ func (c *Client) JSON(v ...interface{}) (interface{}, error) {
 	return c.res.JSON(v...)
 }
 
+// ErrorInto sets the value which the body of a non-ok response is decoded
+// into by JSON and Decode, instead of the value passed to them. The decoded value
+// is returned as the error if it implements error, otherwise it is returned
+// as the Value of a *StatusError.
+func (c *Client) ErrorInto(v interface{}) *Client {
+	c.errInto = v
+
+	return c
+}
+
+// LenientJSON makes JSON decode the response body regardless of the
+// "Content-Type" of the response.
+func (c *Client) LenientJSON() *Client {
+	c.lenientJSON = true
+
+	return c
+}
+
+// UseNumber makes JSON and Decode decode the JSON numbers as json.Number, see
+// Response.UseNumber.
+func (c *Client) UseNumber() *Client {
+	c.jsonOptions.useNumber = true
+
+	return c
+}
+
+// DisallowUnknownFields makes JSON and Decode fail on unknown JSON object
+// keys, see Response.DisallowUnknownFields.
+func (c *Client) DisallowUnknownFields() *Client {
+	c.jsonOptions.disallowUnknownFields = true
+
+	return c
+}
+
+// Decode sends the HTTP request and decodes the reponse body into v, see
+// Response.Decode for details.
+func (c *Client) Decode(v interface{}) error {
+	if _, err := c.End(); err != nil {
+		return err
+	}
+
+	return c.res.Decode(v)
+}
+
 // Text sends the HTTP request and returns the reponse body with text format.
 func (c *Client) Text() (string, error) {
 	if _, err := c.End(); err != nil {
@@ -461,34 +648,35 @@ func (c *Client) Text() (string, error) {
 func (c *Client) assemble() error {
 	c.url.RawQuery = c.queryVals.Encode()
 
-	var buf io.Reader
-
-	if c.mwBuf.Len() != 0 {
-		if c.formVals != nil {
-			for k, vs := range c.formVals {
-				for _, v := range vs {
-					if err := c.mw.WriteField(k, v); err != nil {
-						return err
-					}
-				}
-			}
-		}
+	req, err := http.NewRequest(c.method, c.url.String(), nil)
 
-		buf = c.mwBuf
-		c.Type(c.mw.FormDataContentType())
-		c.mw.Close()
-	} else if c.formVals != nil && c.body == nil {
-		buf = strings.NewReader(c.formVals.Encode())
-	} else {
-		buf = c.body
+	if err != nil {
+		return err
 	}
 
-	req, err := http.NewRequest(c.method, c.url.String(), buf)
+	body, getBody, size, err := c.requestBody()
 
 	if err != nil {
 		return err
 	}
 
+	if body != nil {
+		req.Body = body
+		req.GetBody = getBody
+		req.ContentLength = size
+	}
+
+	if c.ctx != nil {
+		req = req.WithContext(c.ctx)
+	}
+
+	// The response body is decompressed by End if the header is set here,
+	// otherwise by Response.Content and Stream.
+	if c.header.Get(headers.AcceptEncoding) == "" {
+		c.header.Set(headers.AcceptEncoding, acceptEncoding)
+		c.decompress = true
+	}
+
 	c.req = req
 	c.req.Header = c.header
 
@@ -1,6 +1,7 @@
 package request
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/cookiejar"
@@ -505,6 +506,38 @@ func (s *RequestSuite) TestReq() {
 	s.Equal(req.Header.Get(headers.Accept), "application/json")
 }
 
+func (s *RequestSuite) TestEndContext() {
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		<-req.Context().Done()
+	}))
+
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := s.c.
+		Get(server.URL).
+		EndContext(ctx)
+
+	s.NotNil(err)
+	s.Equal(context.DeadlineExceeded, ctx.Err())
+}
+
+func (s *RequestSuite) TestWithContextReq() {
+	type key struct{}
+
+	ctx := context.WithValue(context.Background(), key{}, "v")
+
+	req, err := s.c.
+		Get(testHost).
+		WithContext(ctx).
+		Req()
+
+	s.Nil(err)
+	s.Equal("v", req.Context().Value(key{}))
+}
+
 func TestRequest(t *testing.T) {
 	suite.Run(t, new(RequestSuite))
 }
@@ -1,19 +1,23 @@
 package request
 
 import (
+	"bufio"
 	"bytes"
 	"compress/flate"
 	"compress/gzip"
 	"compress/zlib"
-	"encoding/json"
+	"context"
 	"errors"
 	"io"
 	"io/ioutil"
+	"mime"
 	"net/http"
 	"net/url"
 	"strings"
 
+	"github.com/andybalholm/brotli"
 	"github.com/go-http-utils/headers"
+	"github.com/klauspost/compress/zstd"
 )
 
 // GetIndex searches value from []interface{} by index
@@ -58,17 +62,42 @@ func GetPath(v interface{}, branch ...string) interface{} {
 type Response struct {
 	*http.Response
 
-	raw     *bytes.Buffer
-	content []byte
+	raw         *bytes.Buffer
+	content     []byte
+	errInto     interface{}
+	lenient     bool
+	streamed    bool
+	limits      responseLimits
+	charset     string
+	noTranscode bool
+	jsonOptions jsonOptions
+	// decompressed reports whether Body was decompressed by decompress.
+	decompressed bool
 }
 
-// Raw returns the raw bytes body of the response.
+// Raw returns the raw bytes body of the response. ErrBodyStreamed is
+// returned if the body was streamed before it was read by Raw.
+//
+// The body is decompressed if the "Accept-Encoding" header was sent by
+// default, like Body. If the header was set by the caller, the raw body may
+// still be compressed and Content returns the decompressed one.
 func (r *Response) Raw() ([]byte, error) {
 	if r.raw != nil {
 		return r.raw.Bytes(), nil
 	}
 
-	b, err := ioutil.ReadAll(r.Body)
+	if r.streamed {
+		return nil, ErrBodyStreamed
+	}
+
+	body, err := r.limitRaw(r.body())
+
+	if err != nil {
+		r.Body.Close()
+		return nil, err
+	}
+
+	b, err := ioutil.ReadAll(body)
 	r.Body.Close()
 
 	if err != nil {
@@ -80,8 +109,33 @@ func (r *Response) Raw() ([]byte, error) {
 	return b, nil
 }
 
+// body returns the response body which stops reading once the context of
+// the request is done.
+func (r *Response) body() io.Reader {
+	if r.Request == nil {
+		return r.Body
+	}
+
+	return &contextReader{ctx: r.Request.Context(), r: r.Body}
+}
+
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr *contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return cr.r.Read(p)
+}
+
 // Content returns the content of the response body, it will handle
-// the compression.
+// the compression. The gzip, deflate, br and zstd content encodings are
+// supported, and they are advertised in the "Accept-Encoding" header unless
+// it is set, in which case Body is decompressed by End already.
 func (r *Response) Content() ([]byte, error) {
 	if r.content != nil {
 		return r.content, nil
@@ -93,21 +147,10 @@ func (r *Response) Content() ([]byte, error) {
 		return nil, err
 	}
 
-	var reader io.ReadCloser
+	reader, err := r.decode(r.Header.Get(headers.ContentEncoding), bytes.NewReader(rawBytes))
 
-	switch r.Header.Get(headers.ContentEncoding) {
-	case "gzip":
-		if reader, err = gzip.NewReader(bytes.NewBuffer(r.raw.Bytes())); err != nil {
-			return nil, err
-		}
-	case "deflate":
-		// deflate should be zlib
-		// http://www.gzip.org/zlib/zlib_faq.html#faq38
-		if reader, err = zlib.NewReader(bytes.NewBuffer(r.raw.Bytes())); err != nil {
-			// try RFC 1951 deflate
-			// http: //www.open-open.com/lib/view/open1460866410410.html
-			reader = flate.NewReader(bytes.NewBuffer(r.raw.Bytes()))
-		}
+	if err != nil {
+		return nil, err
 	}
 
 	if reader == nil {
@@ -126,14 +169,189 @@ func (r *Response) Content() ([]byte, error) {
 	return b, nil
 }
 
-// JSON returns the reponse body with JSON format.
+// acceptEncoding is the default "Accept-Encoding" header, which advertises
+// the content encodings supported by decoder.
+const acceptEncoding = "gzip, deflate, br, zstd"
+
+// decoder returns the reader which decompresses r by the content encoding, or
+// nil if the content is not compressed. The encodings of a comma separated
+// list are applied in order, so they are decoded in reverse order. The content
+// is regarded as not compressed if any of the encodings is unknown.
+func decoder(encoding string, r io.Reader) (io.ReadCloser, error) {
+	encodings := contentEncodings(encoding)
+
+	if len(encodings) == 0 {
+		return nil, nil
+	}
+
+	closers := make([]io.Closer, 0, len(encodings))
+	closeAll := func() error {
+		var err error
+
+		for i := len(closers) - 1; i >= 0; i-- {
+			if closeErr := closers[i].Close(); err == nil {
+				err = closeErr
+			}
+		}
+
+		return err
+	}
+
+	for i := len(encodings) - 1; i >= 0; i-- {
+		rc, err := decodeOne(encodings[i], r)
+
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+
+		closers = append(closers, rc)
+		r = rc
+	}
+
+	return &readCloser{Reader: r, close: closeAll}, nil
+}
+
+// contentEncodings returns the encodings of a comma separated list, or nil if
+// there is none or any of them is unknown.
+func contentEncodings(encoding string) []string {
+	var encodings []string
+
+	for _, e := range strings.Split(encoding, ",") {
+		e = strings.ToLower(strings.TrimSpace(e))
+
+		switch e {
+		case "", "identity":
+		case "gzip", "x-gzip", "deflate", "br", "zstd":
+			encodings = append(encodings, e)
+		default:
+			return nil
+		}
+	}
+
+	return encodings
+}
+
+// decompress makes Body read the decompressed body, like the transport does
+// when it sends the "Accept-Encoding" header by itself. The
+// "Content-Encoding" and "Content-Length" headers are removed, and the limits
+// of the body as received are applied under the decompression.
+func (r *Response) decompress() {
+	encoding := r.Header.Get(headers.ContentEncoding)
+
+	if len(contentEncodings(encoding)) == 0 {
+		return
+	}
+
+	raw, err := r.limitRaw(r.Body)
+	body := r.Body
+
+	r.Body = &lazyBody{body: body, open: func() (io.ReadCloser, error) {
+		if err != nil {
+			return nil, err
+		}
+
+		return r.decode(encoding, raw)
+	}}
+
+	r.Header.Del(headers.ContentEncoding)
+	r.Header.Del(headers.ContentLength)
+	r.ContentLength = -1
+	r.Uncompressed = true
+	r.decompressed = true
+}
+
+// lazyBody reads the reader returned by open, which is called on the first
+// Read, so nothing is read from body until then.
+type lazyBody struct {
+	body io.ReadCloser
+	open func() (io.ReadCloser, error)
+	rc   io.ReadCloser
+	err  error
+}
+
+func (b *lazyBody) Read(p []byte) (int, error) {
+	if b.rc == nil && b.err == nil {
+		b.rc, b.err = b.open()
+	}
+
+	if b.err != nil {
+		return 0, b.err
+	}
+
+	return b.rc.Read(p)
+}
+
+func (b *lazyBody) Close() error {
+	if b.rc != nil {
+		b.rc.Close()
+	}
+
+	return b.body.Close()
+}
+
+func decodeOne(encoding string, r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+
+	// An empty body, such as the body of a HEAD or 204 response which still
+	// has the "Content-Encoding" header, is empty content rather than a
+	// truncated stream.
+	if _, err := br.Peek(1); err == io.EOF {
+		return ioutil.NopCloser(br), nil
+	}
+
+	switch encoding {
+	case "gzip", "x-gzip":
+		return gzip.NewReader(br)
+	case "deflate":
+		// deflate should be zlib
+		// http://www.gzip.org/zlib/zlib_faq.html#faq38
+		if header, err := br.Peek(2); err == nil && isZlibHeader(header) {
+			return zlib.NewReader(br)
+		}
+
+		// try RFC 1951 deflate
+		// http: //www.open-open.com/lib/view/open1460866410410.html
+		return flate.NewReader(br), nil
+	case "br":
+		return ioutil.NopCloser(brotli.NewReader(br)), nil
+	}
+
+	zr, err := zstd.NewReader(br)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return zr.IOReadCloser(), nil
+}
+
+func isZlibHeader(header []byte) bool {
+	return header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0
+}
+
+// JSON returns the reponse body with JSON format. If the status code is not
+// ok, the decoded body is returned along with a *StatusError, unless the value
+// set by Client.ErrorInto is used to decode the body instead. A problem
+// details body ("application/problem+json") of a non-ok response is returned
+// as a *Problem error.
+//
+// The "Content-Type" of the response must be a JSON media type, such as
+// "application/json", "text/json" or any "+json" structured syntax suffix
+// type like "application/vnd.api+json", unless Client.LenientJSON is used.
 func (r *Response) JSON(v ...interface{}) (interface{}, error) {
 	b, err := r.Content()
 	if err != nil {
 		return nil, err
 	}
 
-	if !strings.HasPrefix(r.Header.Get(headers.ContentType), "application/json") {
+	typ := mediaType(r.Header.Get(headers.ContentType))
+
+	if !r.lenient && !isJSON(typ) {
+		if !r.OK() {
+			return nil, newStatusError(r, b)
+		}
+
 		err := r.Status
 		if len(b) > 0 {
 			err = string(b)
@@ -141,6 +359,14 @@ func (r *Response) JSON(v ...interface{}) (interface{}, error) {
 		return nil, errors.New(err)
 	}
 
+	if !r.OK() && r.errInto != nil {
+		return nil, r.decodeError(jsonCodec{}, b)
+	}
+
+	if typ == problemJSON && !r.OK() {
+		return nil, r.problem(b)
+	}
+
 	var res interface{}
 	if len(v) > 0 {
 		res = v[0]
@@ -148,18 +374,129 @@ func (r *Response) JSON(v ...interface{}) (interface{}, error) {
 		res = new(map[string]interface{})
 	}
 
-	if err = json.Unmarshal(b, res); err != nil {
+	if err = (jsonCodec{r.jsonOptions}).Unmarshal(b, res); err != nil {
+		if !r.OK() {
+			return nil, newStatusError(r, b)
+		}
+
 		return nil, err
 	}
 
 	if !r.OK() {
-		return res, ErrStatusNotOk
+		return res, newStatusError(r, b)
 	}
 
 	return res, nil
 }
 
-// Text returns the reponse body with text format.
+// UseNumber makes JSON and Decode decode the JSON numbers into interface{}
+// values as json.Number instead of float64, so large integers such as IDs
+// keep their precision.
+func (r *Response) UseNumber() *Response {
+	r.jsonOptions.useNumber = true
+
+	return r
+}
+
+// DisallowUnknownFields makes JSON and Decode fail when a JSON object has a
+// key which doesn't match any exported field of the struct decoded into.
+func (r *Response) DisallowUnknownFields() *Response {
+	r.jsonOptions.disallowUnknownFields = true
+
+	return r
+}
+
+// mediaType returns the lower-cased media type of the given "Content-Type"
+// header value, without its parameters.
+func mediaType(contentType string) string {
+	typ, _, err := mime.ParseMediaType(contentType)
+
+	if err != nil && err != mime.ErrInvalidMediaParameter {
+		return ""
+	}
+
+	return typ
+}
+
+func isJSON(typ string) bool {
+	return typ == "application/json" || typ == "text/json" ||
+		strings.HasSuffix(typ, "+json")
+}
+
+// Decode decodes the reponse body into v by the codec registered for the
+// "Content-Type" of the response, ErrNoCodec is returned if there is none,
+// unless Client.LenientJSON is used. Non-ok responses, Client.ErrorInto and
+// problem details bodies are handled in the same way as JSON.
+func (r *Response) Decode(v interface{}) error {
+	b, err := r.Content()
+
+	if err != nil {
+		return err
+	}
+
+	typ := mediaType(r.Header.Get(headers.ContentType))
+	codec, ok := codecFor(typ)
+
+	if !ok {
+		if !r.lenient {
+			if !r.OK() {
+				return newStatusError(r, b)
+			}
+
+			return ErrNoCodec
+		}
+
+		codec = jsonCodec{}
+	}
+
+	if !r.OK() && r.errInto != nil {
+		return r.decodeError(codec, b)
+	}
+
+	if _, ok := codec.(jsonCodec); ok {
+		codec = jsonCodec{r.jsonOptions}
+	}
+
+	if typ == problemJSON && !r.OK() {
+		return r.problem(b)
+	}
+
+	if err = codec.Unmarshal(b, v); err != nil {
+		if !r.OK() {
+			return newStatusError(r, b)
+		}
+
+		return err
+	}
+
+	if !r.OK() {
+		return newStatusError(r, b)
+	}
+
+	return nil
+}
+
+func (r *Response) decodeError(codec Codec, b []byte) error {
+	if err := codec.Unmarshal(b, r.errInto); err != nil {
+		return newStatusError(r, b)
+	}
+
+	if err, ok := r.errInto.(error); ok {
+		return err
+	}
+
+	statusErr := newStatusError(r, b)
+	statusErr.Value = r.errInto
+
+	return statusErr
+}
+
+// Text returns the reponse body with text format, which is transcoded to
+// UTF-8 from the charset of the body, unless Client.NoTranscode is used. The
+// charset is the one set by Client.Charset, or the charset of the
+// "Content-Type" header, or detected from the byte order mark, or declared by
+// the meta tag of a HTML body, in that order. If the status code is not ok,
+// the body is returned along with a *StatusError.
 func (r *Response) Text() (string, error) {
 	b, err := r.Content()
 
@@ -167,11 +504,17 @@ func (r *Response) Text() (string, error) {
 		return "", err
 	}
 
+	text, err := r.transcode(b)
+
+	if err != nil {
+		return "", err
+	}
+
 	if !r.OK() {
-		return string(b), ErrStatusNotOk
+		return string(text), newStatusError(r, b)
 	}
 
-	return string(b), nil
+	return string(text), nil
 }
 
 // URL returns url of the final request.
@@ -1,8 +1,11 @@
 package request
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"testing"
 
 	"github.com/go-http-utils/headers"
@@ -40,6 +43,32 @@ func (s *ResponseSuite) TestRaw() {
 	s.Equal(raw1, raw2)
 }
 
+func (s *ResponseSuite) TestRawContextCancel() {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.Write([]byte("partial"))
+		res.(http.Flusher).Flush()
+
+		select {
+		case <-req.Context().Done():
+		case <-done:
+		}
+	}))
+
+	defer server.Close()
+	defer close(done)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	res, err := s.c.Get(server.URL).EndContext(ctx)
+	s.Nil(err)
+
+	cancel()
+
+	_, err = res.Raw()
+	s.Equal(context.Canceled, err)
+}
+
 func (s *ResponseSuite) TestContent() {
 	res, err := s.c.Get(testHost).End()
 	s.Nil(err)
@@ -121,7 +150,60 @@ func (s *ResponseSuite) TestNotOkText() {
 		Get(testHost + "/post").
 		Text()
 
-	s.Equal(ErrStatusNotOk, err)
+	s.ErrorIs(err, ErrStatusNotOk)
+	s.Equal(http.StatusMethodNotAllowed, err.(*StatusError).StatusCode)
+}
+
+func (s *ResponseSuite) TestJSONMediaTypes() {
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.Header().Set(headers.ContentType, req.URL.Query().Get("type"))
+		res.Write([]byte(`{"k1":"v1"}`))
+	}))
+
+	defer server.Close()
+
+	for _, typ := range []string{
+		"application/json",
+		"Application/JSON; charset=utf-8",
+		"text/json",
+		"application/vnd.api+json",
+		"application/hal+json",
+		"application/ld+json; profile=\"http://www.w3.org/ns/json-ld#compacted\"",
+	} {
+		j, err := New().
+			Get(server.URL).
+			Query(url.Values{"type": []string{typ}}).
+			JSON()
+
+		s.Nil(err, typ)
+		s.Equal("v1", GetPath(j, "k1"), typ)
+	}
+
+	for _, typ := range []string{"text/plain", "application/jsonp", ""} {
+		_, err := New().
+			Get(server.URL).
+			Query(url.Values{"type": []string{typ}}).
+			JSON()
+
+		s.EqualError(err, `{"k1":"v1"}`, typ)
+	}
+}
+
+func (s *ResponseSuite) TestLenientJSON() {
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.Header().Set(headers.ContentType, "text/plain")
+		res.Write([]byte(`{"k1":"v1"}`))
+	}))
+
+	defer server.Close()
+
+	j, err := s.c.
+		Get(server.URL).
+		LenientJSON().
+		JSON()
+
+	s.Nil(err)
+	s.Equal("v1", GetPath(j, "k1"))
 }
 
 func (s *ResponseSuite) TestGetIndex() {
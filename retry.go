@@ -0,0 +1,245 @@
+package request
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/go-http-utils/headers"
+)
+
+// maxDrainBytes is the max number of bytes of a failed response body which
+// are drained before the request is retried.
+const maxDrainBytes = 64 << 10
+
+// RetryPolicy describes when and how a failed request is retried. A policy
+// must not be modified once it is used by a Client or an Agent.
+type RetryPolicy struct {
+	// MaxAttempts is the max number of attempts, including the first one.
+	MaxAttempts int
+	// MinBackoff is the wait before the first retry, it doubles after each
+	// retry.
+	MinBackoff time.Duration
+	// MaxBackoff caps the wait between two attempts. If the "Retry-After"
+	// header of a response asks for a longer wait, the response is returned
+	// as it is instead of being retried.
+	MaxBackoff time.Duration
+	// Jitter randomizes each wait by up to the given fraction of it, for
+	// example 0.2 means ±20%.
+	Jitter float64
+	// StatusCodes are the response status codes which are retried.
+	StatusCodes []int
+	// RetryError reports whether an error returned by the transport should be
+	// retried. If nil, timeouts, connection failures and unexpected EOFs are
+	// retried, while the cancellation of the request context is not.
+	RetryError func(err error) bool
+}
+
+// NewRetryPolicy returns a RetryPolicy with at most maxAttempts attempts, an
+// exponential backoff from 100ms up to 10s with 20% jitter, which retries
+// transient network errors and the 429, 502, 503 and 504 status codes.
+func NewRetryPolicy(maxAttempts int) *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: maxAttempts,
+		MinBackoff:  100 * time.Millisecond,
+		MaxBackoff:  10 * time.Second,
+		Jitter:      0.2,
+		StatusCodes: []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// Retry sets the retry policy of the request. When the request is retried,
+// its body is replayed from the start, which works for the bodies set by
+// Send, Field and Attach.
+func (c *Client) Retry(policy *RetryPolicy) *Client {
+	c.retry = policy
+
+	return c
+}
+
+// Retry sets the default retry policy of the spawned requests.
+func (a *Agent) Retry(policy *RetryPolicy) *Agent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.retry = policy
+
+	return a
+}
+
+func (p *RetryPolicy) do(cli *http.Client, req *http.Request) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		res, err := cli.Do(req)
+
+		if attempt >= p.MaxAttempts || !p.shouldRetry(req, res, err) {
+			return res, err
+		}
+
+		wait := p.backoff(attempt)
+
+		if res != nil {
+			if after, ok := retryAfter(res); ok {
+				if p.MaxBackoff > 0 && after > p.MaxBackoff {
+					return res, nil
+				}
+
+				if after > wait {
+					wait = after
+				}
+			}
+
+			// Drains a bounded part of the body, so the connection may be
+			// reused without reading a large body in full.
+			io.CopyN(ioutil.Discard, res.Body, maxDrainBytes)
+			res.Body.Close()
+		}
+
+		if err := sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+
+			if err != nil {
+				return nil, err
+			}
+
+			req.Body = body
+		}
+	}
+}
+
+func (p *RetryPolicy) shouldRetry(req *http.Request, res *http.Response, err error) bool {
+	if req.Context().Err() != nil {
+		return false
+	}
+
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+
+	if err != nil {
+		if p.RetryError != nil {
+			return p.RetryError(err)
+		}
+
+		return retryableError(err)
+	}
+
+	for _, code := range p.StatusCodes {
+		if res.StatusCode == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	wait := p.MinBackoff
+
+	for i := 1; i < attempt && (p.MaxBackoff <= 0 || wait < p.MaxBackoff); i++ {
+		wait *= 2
+	}
+
+	if p.MaxBackoff > 0 && wait > p.MaxBackoff {
+		wait = p.MaxBackoff
+	}
+
+	if p.Jitter > 0 {
+		wait += time.Duration((rand.Float64()*2 - 1) * p.Jitter * float64(wait))
+	}
+
+	return wait
+}
+
+// retryAfter parses the "Retry-After" header, which is either a number of
+// seconds or a HTTP date.
+func retryAfter(res *http.Response) (time.Duration, bool) {
+	v := res.Header.Get(headers.RetryAfter)
+
+	if v == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(v); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t), true
+	}
+
+	return 0, false
+}
+
+func retryableError(err error) bool {
+	for {
+		switch e := err.(type) {
+		case *url.Error:
+			err = e.Err
+			continue
+		case *net.OpError:
+			// A host which can't be resolved is not likely to be resolved
+			// soon, unless the lookup timed out.
+			if dnsErr, ok := e.Err.(*net.DNSError); ok {
+				return dnsErr.IsTimeout
+			}
+
+			if e.Timeout() || e.Op == "dial" {
+				return true
+			}
+
+			err = e.Err
+			continue
+		case *os.SyscallError:
+			err = e.Err
+			continue
+		}
+
+		break
+	}
+
+	switch err {
+	case context.Canceled, context.DeadlineExceeded:
+		return false
+	case io.EOF, io.ErrUnexpectedEOF, syscall.ECONNRESET, syscall.ECONNREFUSED:
+		return true
+	}
+
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		return true
+	}
+
+	return false
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,211 @@
+package request
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/go-http-utils/headers"
+	"github.com/stretchr/testify/suite"
+)
+
+type RetrySuite struct {
+	suite.Suite
+
+	policy *RetryPolicy
+}
+
+func (s *RetrySuite) SetupTest() {
+	s.policy = NewRetryPolicy(3)
+	s.policy.MinBackoff = time.Millisecond
+	s.policy.MaxBackoff = 10 * time.Millisecond
+}
+
+// failingServer responds with status for the first failures requests, and
+// with the request body afterwards.
+func failingServer(failures int32, status int, attempts *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		b, _ := ioutil.ReadAll(req.Body)
+
+		if atomic.AddInt32(attempts, 1) <= failures {
+			res.WriteHeader(status)
+			return
+		}
+
+		res.Write(b)
+	}))
+}
+
+func (s *RetrySuite) TestRetryStatus() {
+	var attempts int32
+	server := failingServer(2, http.StatusServiceUnavailable, &attempts)
+	defer server.Close()
+
+	res, err := Get(server.URL).Retry(s.policy).End()
+
+	s.Nil(err)
+	s.Equal(http.StatusOK, res.StatusCode)
+	s.Equal(int32(3), attempts)
+}
+
+func (s *RetrySuite) TestRetryExhausted() {
+	var attempts int32
+	server := failingServer(5, http.StatusServiceUnavailable, &attempts)
+	defer server.Close()
+
+	res, err := Get(server.URL).Retry(s.policy).End()
+
+	s.Nil(err)
+	s.Equal(http.StatusServiceUnavailable, res.StatusCode)
+	s.Equal(int32(3), attempts)
+}
+
+func (s *RetrySuite) TestNotRetryStatus() {
+	var attempts int32
+	server := failingServer(1, http.StatusInternalServerError, &attempts)
+	defer server.Close()
+
+	res, err := Get(server.URL).Retry(s.policy).End()
+
+	s.Nil(err)
+	s.Equal(http.StatusInternalServerError, res.StatusCode)
+	s.Equal(int32(1), attempts)
+}
+
+func (s *RetrySuite) TestReplaySend() {
+	var attempts int32
+	server := failingServer(2, http.StatusBadGateway, &attempts)
+	defer server.Close()
+
+	text, err := Post(server.URL).
+		Send(map[string]string{"k1": "v1"}).
+		Retry(s.policy).
+		Text()
+
+	s.Nil(err)
+	s.Equal(`{"k1":"v1"}`, text)
+}
+
+func (s *RetrySuite) TestReplayField() {
+	var attempts int32
+	server := failingServer(2, http.StatusBadGateway, &attempts)
+	defer server.Close()
+
+	text, err := Post(server.URL).
+		Field(url.Values{"k1": []string{"v1"}}).
+		Retry(s.policy).
+		Text()
+
+	s.Nil(err)
+	s.Equal("k1=v1", text)
+}
+
+func (s *RetrySuite) TestReplayAttach() {
+	var attempts int32
+	server := failingServer(2, http.StatusBadGateway, &attempts)
+	defer server.Close()
+
+	text, err := Post(server.URL).
+		Attach("test.md", "./README.md", "README.md").
+		Retry(s.policy).
+		Text()
+
+	s.Nil(err)
+	s.Contains(text, "A concise HTTP request client for Go")
+}
+
+func (s *RetrySuite) TestRetryAfter() {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		res.Header().Set(headers.RetryAfter, "1")
+		res.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	res, err := Get(server.URL).Retry(s.policy).End()
+
+	s.Nil(err)
+	s.Equal(http.StatusTooManyRequests, res.StatusCode)
+	s.Equal(int32(1), attempts)
+
+	s.policy.MaxBackoff = 2 * time.Second
+	start := time.Now()
+
+	_, err = Get(server.URL).Retry(s.policy).End()
+
+	s.Nil(err)
+	s.True(time.Since(start) >= 2*time.Second)
+	s.Equal(int32(4), attempts)
+}
+
+func (s *RetrySuite) TestRetryError() {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			conn, _, _ := res.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+	}))
+	defer server.Close()
+
+	res, err := Get(server.URL).Retry(s.policy).End()
+
+	s.Nil(err)
+	s.Equal(http.StatusOK, res.StatusCode)
+	s.Equal(int32(2), attempts)
+}
+
+func (s *RetrySuite) TestRetryableError() {
+	opError := func(op string, err error) error {
+		return &url.Error{Op: "Get", URL: "http://mysite.com", Err: &net.OpError{Op: op, Net: "tcp", Err: err}}
+	}
+
+	s.False(retryableError(opError("dial", &net.DNSError{Err: "no such host", Name: "mysite.com", IsNotFound: true})))
+	s.True(retryableError(opError("dial", &net.DNSError{Err: "i/o timeout", Name: "mysite.com", IsTimeout: true})))
+	s.True(retryableError(opError("dial", &os.SyscallError{Syscall: "connect", Err: syscall.ECONNREFUSED})))
+	s.True(retryableError(opError("read", &os.SyscallError{Syscall: "read", Err: syscall.ECONNRESET})))
+	s.False(retryableError(opError("write", &os.SyscallError{Syscall: "write", Err: syscall.EACCES})))
+}
+
+func (s *RetrySuite) TestRetryContext() {
+	var attempts int32
+	server := failingServer(5, http.StatusServiceUnavailable, &attempts)
+	defer server.Close()
+
+	s.policy.MinBackoff = time.Minute
+	s.policy.MaxBackoff = time.Minute
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := Get(server.URL).Retry(s.policy).EndContext(ctx)
+
+	s.Equal(context.DeadlineExceeded, err)
+	s.Equal(int32(1), attempts)
+}
+
+func (s *RetrySuite) TestAgentRetry() {
+	var attempts int32
+	server := failingServer(1, http.StatusServiceUnavailable, &attempts)
+	defer server.Close()
+
+	res, err := NewAgent().Retry(s.policy).Get(server.URL).End()
+
+	s.Nil(err)
+	s.Equal(http.StatusOK, res.StatusCode)
+	s.Equal(int32(2), attempts)
+}
+
+func TestRetry(t *testing.T) {
+	suite.Run(t, new(RetrySuite))
+}
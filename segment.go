@@ -0,0 +1,239 @@
+package request
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/go-http-utils/headers"
+)
+
+// segmentAttempts is the max number of attempts to download a segment.
+const segmentAttempts = 3
+
+// Segments makes Download split the body into n byte ranges which are
+// downloaded at the same time over separate connections, and then reassembled
+// into one file. A HEAD request is sent first to discover the size of the
+// body and whether the server supports range requests, if it doesn't, the
+// body is downloaded in one piece as usual.
+//
+// A segment which fails is retried individually from where it stopped.
+// Unlike the single piece download, an interrupted segmented download is not
+// resumed by a later call.
+func (c *Client) Segments(n int) *Client {
+	c.segments = n
+
+	return c
+}
+
+func (c *Client) downloadSegments(path string) (*Response, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	head := c.clone()
+	head.method = http.MethodHead
+	head.Set(headers.AcceptEncoding, "identity")
+
+	res, err := head.End()
+
+	if err != nil {
+		return nil, err
+	}
+
+	res.Body.Close()
+
+	if !res.OK() {
+		return res, newStatusError(res, nil)
+	}
+
+	size := res.ContentLength
+
+	if size < int64(c.segments) || res.Header.Get(headers.AcceptRanges) != "bytes" {
+		return c.download(path)
+	}
+
+	hashes, err := newChecksums(c.sha256, res)
+
+	if err != nil {
+		return res, err
+	}
+
+	validator := res.Header.Get(headers.ETag)
+
+	if validator == "" || strings.HasPrefix(validator, "W/") {
+		validator = res.Header.Get(headers.LastModified)
+	}
+
+	partPath := path + partSuffix
+	file, err := os.Create(partPath)
+
+	if err != nil {
+		return res, err
+	}
+
+	if err = file.Truncate(size); err != nil {
+		file.Close()
+		os.Remove(partPath)
+		return res, err
+	}
+
+	var wg sync.WaitGroup
+	var p *progress
+
+	if c.downloadProgress != nil {
+		p = &progress{total: size, fn: c.downloadProgress}
+	}
+
+	// The number of segments may be less than c.segments, since the last
+	// segments may be empty, such as 4 segments of 9 bytes.
+	segmentSize := (size + int64(c.segments) - 1) / int64(c.segments)
+	segments := int((size + segmentSize - 1) / segmentSize)
+
+	parent := c.ctx
+
+	if parent == nil {
+		parent = context.Background()
+	}
+
+	// The other segments are cancelled once a segment fails for good.
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	var mu sync.Mutex
+	var firstErr error
+
+	for i := 0; i < segments; i++ {
+		start := int64(i) * segmentSize
+		end := start + segmentSize - 1
+
+		if end >= size {
+			end = size - 1
+		}
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			w := &offsetWriter{w: file, off: start, progress: p}
+
+			if err := c.downloadSegment(ctx, w, end, validator); err != nil {
+				mu.Lock()
+				defer mu.Unlock()
+
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if err = file.Close(); firstErr == nil {
+		firstErr = err
+	}
+
+	if firstErr != nil {
+		os.Remove(partPath)
+		return res, firstErr
+	}
+
+	if len(hashes) > 0 {
+		if err = hashFile(partPath, hashes); err != nil {
+			os.Remove(partPath)
+			return res, err
+		}
+
+		if !hashes.verify() {
+			os.Remove(partPath)
+			return res, ErrChecksumMismatch
+		}
+	}
+
+	if err = os.Rename(partPath, path); err != nil {
+		return res, err
+	}
+
+	return res, nil
+}
+
+// downloadSegment downloads the byte range [w.off, end] of the body into w,
+// retrying from where it stopped if reading the body fails.
+func (c *Client) downloadSegment(ctx context.Context, w *offsetWriter, end int64, validator string) error {
+	for attempt := 1; ; attempt++ {
+		retry, err := c.fetchSegment(ctx, w, end, validator)
+
+		if err == nil || !retry || attempt >= segmentAttempts {
+			return err
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+// fetchSegment requests the byte range [w.off, end] and writes it to w. It
+// reports whether the failure, if any, is worth retrying.
+func (c *Client) fetchSegment(ctx context.Context, w *offsetWriter, end int64, validator string) (bool, error) {
+	seg := c.clone().WithContext(ctx)
+	seg.Set(headers.AcceptEncoding, "identity")
+	seg.Set(headers.Range, fmt.Sprintf("bytes=%d-%d", w.off, end))
+
+	if validator != "" {
+		seg.Set(headers.IfRange, validator)
+	}
+
+	res, err := seg.End()
+
+	if err != nil {
+		return true, err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusPartialContent {
+		if !res.OK() {
+			return false, newStatusError(res, nil)
+		}
+
+		return false, fmt.Errorf("request: %s ignored the range request", res.Request.URL)
+	}
+
+	if start, _, _, ok := parseContentRange(res.Header.Get(headers.ContentRange)); !ok || start != w.off {
+		return false, fmt.Errorf("request: unexpected content range %q",
+			res.Header.Get(headers.ContentRange))
+	}
+
+	if _, err = io.Copy(w, io.LimitReader(res.body(), end-w.off+1)); err != nil {
+		return true, err
+	}
+
+	if w.off <= end {
+		return true, io.ErrUnexpectedEOF
+	}
+
+	return false, nil
+}
+
+// offsetWriter writes to w from the offset off.
+type offsetWriter struct {
+	w        io.WriterAt
+	off      int64
+	progress *progress
+}
+
+func (ow *offsetWriter) Write(p []byte) (int, error) {
+	n, err := ow.w.WriteAt(p, ow.off)
+	ow.off += int64(n)
+	ow.progress.add(n)
+
+	return n, err
+}
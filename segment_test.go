@@ -0,0 +1,218 @@
+package request
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-http-utils/headers"
+	"github.com/stretchr/testify/suite"
+)
+
+type SegmentSuite struct {
+	suite.Suite
+
+	mu      sync.Mutex
+	content []byte
+	methods []string
+	failed  map[string]bool
+	dir     string
+	path    string
+	server  *httptest.Server
+}
+
+func (s *SegmentSuite) SetupTest() {
+	s.content = []byte(strings.Repeat("0123456789", 10007))
+	s.methods = nil
+	s.failed = make(map[string]bool)
+	s.server = httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		rng := req.Header.Get(headers.Range)
+		end := rng[strings.IndexByte(rng, '-')+1:]
+
+		// The first request of each segment fails, and a resumed request has
+		// the same end as the segment.
+		s.mu.Lock()
+		s.methods = append(s.methods, req.Method)
+		fail := req.URL.Path == "/flaky" && rng != "" && !s.failed[end]
+		s.failed[end] = true
+		s.mu.Unlock()
+
+		switch req.URL.Path {
+		case "/sized":
+			n, _ := strconv.Atoi(req.URL.Query().Get("n"))
+			http.ServeContent(res, req, "", time.Time{}, bytes.NewReader(s.content[:n]))
+			return
+		case "/fail-fast":
+			if req.Method == http.MethodHead {
+				http.ServeContent(res, req, "", time.Time{}, bytes.NewReader(s.content))
+				return
+			}
+
+			// The first segment fails for good, and the others are stalled
+			// until they are cancelled.
+			if strings.HasPrefix(rng, "bytes=0-") {
+				http.NotFound(res, req)
+				return
+			}
+
+			<-req.Context().Done()
+			return
+		case "/no-range":
+			res.Write(s.content)
+			return
+		case "/ignore-range":
+			if req.Method == http.MethodHead {
+				res.Header().Set(headers.AcceptRanges, "bytes")
+				res.Header().Set(headers.ContentLength, "100070")
+				return
+			}
+
+			res.Write(s.content)
+			return
+		}
+
+		if fail {
+			// Sends a half of the segment, then breaks the connection.
+			start, end, _, _ := parseContentRange("bytes " + strings.TrimPrefix(rng, "bytes=") + "/*")
+			res.Header().Set(headers.ContentRange, fmt.Sprintf("bytes %d-%d/%d", start, end, len(s.content)))
+			res.Header().Set(headers.ContentLength, strconv.FormatInt(end-start+1, 10))
+			res.WriteHeader(http.StatusPartialContent)
+			res.Write(s.content[start : start+(end-start)/2])
+			res.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+
+		res.Header().Set(headers.ETag, `"v1"`)
+		http.ServeContent(res, req, "", time.Time{}, bytes.NewReader(s.content))
+	}))
+
+	dir, err := ioutil.TempDir("", "request")
+	s.Nil(err)
+
+	s.dir = dir
+	s.path = filepath.Join(dir, "file")
+}
+
+func (s *SegmentSuite) TearDownTest() {
+	s.server.Close()
+	os.RemoveAll(s.dir)
+}
+
+func (s *SegmentSuite) assertDownloaded() {
+	b, err := ioutil.ReadFile(s.path)
+
+	s.Nil(err)
+	s.True(bytes.Equal(s.content, b))
+
+	_, err = os.Stat(s.path + partSuffix)
+	s.True(os.IsNotExist(err))
+}
+
+func (s *SegmentSuite) TestSegments() {
+	sum := sha256.Sum256(s.content)
+
+	_, err := Get(s.server.URL).
+		Segments(4).
+		ExpectSHA256(hex.EncodeToString(sum[:])).
+		Download(s.path)
+
+	s.Nil(err)
+	s.Equal(http.MethodHead, s.methods[0])
+	s.Len(s.methods, 5)
+	s.assertDownloaded()
+}
+
+func (s *SegmentSuite) TestRetrySegment() {
+	_, err := Get(s.server.URL + "/flaky").Segments(3).Download(s.path)
+
+	s.Nil(err)
+	s.Len(s.methods, 7)
+	s.assertDownloaded()
+}
+
+func (s *SegmentSuite) TestSegmentsProgress() {
+	var received, total int64
+
+	_, err := Get(s.server.URL + "/flaky").
+		Segments(3).
+		OnDownloadProgress(func(n, t int64) {
+			s.True(n > received)
+			received, total = n, t
+		}).
+		Download(s.path)
+
+	s.Nil(err)
+	s.Equal(int64(len(s.content)), received)
+	s.Equal(int64(len(s.content)), total)
+	s.assertDownloaded()
+}
+
+func (s *SegmentSuite) TestNoRange() {
+	_, err := Get(s.server.URL + "/no-range").Segments(4).Download(s.path)
+
+	s.Nil(err)
+	s.Equal([]string{http.MethodHead, http.MethodGet}, s.methods)
+	s.assertDownloaded()
+}
+
+func (s *SegmentSuite) TestIgnoreRange() {
+	_, err := Get(s.server.URL + "/ignore-range").Segments(2).Download(s.path)
+
+	s.Contains(err.Error(), "ignored the range request")
+
+	_, err = os.Stat(s.path + partSuffix)
+	s.True(os.IsNotExist(err))
+}
+
+func (s *SegmentSuite) TestChecksumMismatch() {
+	_, err := Get(s.server.URL).
+		Segments(4).
+		ExpectSHA256(hex.EncodeToString(make([]byte, sha256.Size))).
+		Download(s.path)
+
+	s.Equal(ErrChecksumMismatch, err)
+
+	_, err = os.Stat(s.path)
+	s.True(os.IsNotExist(err))
+}
+
+func (s *SegmentSuite) TestUnevenSegments() {
+	for _, c := range []struct{ size, segments int }{{9, 4}, {10, 6}, {225, 16}, {3, 8}} {
+		s.content = []byte(strings.Repeat("0123456789", 100))[:c.size]
+		path := fmt.Sprintf("%s?n=%d", s.server.URL+"/sized", c.size)
+
+		_, err := Get(path).Segments(c.segments).Download(s.path)
+
+		s.Nil(err)
+
+		b, err := ioutil.ReadFile(s.path)
+		s.Nil(err)
+		s.Equal(s.content, b)
+	}
+}
+
+func (s *SegmentSuite) TestCancelSegments() {
+	start := time.Now()
+	_, err := Get(s.server.URL + "/fail-fast").Segments(4).Download(s.path)
+
+	s.Equal(http.StatusNotFound, err.(*StatusError).StatusCode)
+	s.True(time.Since(start) < 5*time.Second)
+
+	_, err = os.Stat(s.path + partSuffix)
+	s.True(os.IsNotExist(err))
+}
+
+func TestSegment(t *testing.T) {
+	suite.Run(t, new(SegmentSuite))
+}
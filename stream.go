@@ -0,0 +1,104 @@
+package request
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/go-http-utils/headers"
+)
+
+type readCloser struct {
+	io.Reader
+	close func() error
+}
+
+func (rc *readCloser) Close() error {
+	return rc.close()
+}
+
+// Stream returns the reader of the decompressed response body, which reads
+// the body on demand instead of buffering all of it in memory, the caller
+// must close it. Once the body is streamed, Raw, Content and the methods
+// based on them return ErrBodyStreamed, unless the body was already read by
+// them, in which case the cached body is streamed instead.
+func (r *Response) Stream() (io.ReadCloser, error) {
+	encoding := r.Header.Get(headers.ContentEncoding)
+
+	if r.raw != nil {
+		raw := bytes.NewReader(r.raw.Bytes())
+		rc, err := r.decode(encoding, raw)
+
+		if err == nil && rc == nil {
+			return ioutil.NopCloser(raw), nil
+		}
+
+		return rc, err
+	}
+
+	if r.streamed {
+		return nil, ErrBodyStreamed
+	}
+
+	r.streamed = true
+	body, err := r.limitRaw(r.body())
+
+	if err != nil {
+		r.Body.Close()
+		return nil, err
+	}
+
+	rc, err := r.decode(encoding, body)
+
+	if err != nil {
+		r.Body.Close()
+		return nil, err
+	}
+
+	if rc == nil {
+		return &readCloser{Reader: body, close: r.Body.Close}, nil
+	}
+
+	return &readCloser{Reader: rc, close: func() error {
+		rc.Close()
+		return r.Body.Close()
+	}}, nil
+}
+
+// WriteTo streams the decompressed response body to w, it implements
+// io.WriterTo.
+func (r *Response) WriteTo(w io.Writer) (int64, error) {
+	stream, err := r.Stream()
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer stream.Close()
+
+	return io.Copy(w, stream)
+}
+
+// SaveTo streams the decompressed response body to the file at path, the file
+// is removed if the body can't be fully saved.
+func (r *Response) SaveTo(path string) error {
+	file, err := os.Create(path)
+
+	if err != nil {
+		return err
+	}
+
+	if _, err = r.WriteTo(file); err != nil {
+		file.Close()
+		os.Remove(path)
+		return err
+	}
+
+	if err = file.Close(); err != nil {
+		os.Remove(path)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,242 @@
+package request
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/go-http-utils/headers"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/suite"
+)
+
+type StreamSuite struct {
+	suite.Suite
+
+	body   string
+	server *httptest.Server
+}
+
+func (s *StreamSuite) SetupTest() {
+	s.body = strings.Repeat("request", 100000)
+	s.server = httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		var w io.WriteCloser
+
+		switch req.URL.Path {
+		case "/gzip":
+			res.Header().Set(headers.ContentEncoding, "gzip")
+			w = gzip.NewWriter(res)
+		case "/zlib":
+			res.Header().Set(headers.ContentEncoding, "deflate")
+			w = zlib.NewWriter(res)
+		case "/deflate":
+			res.Header().Set(headers.ContentEncoding, "deflate")
+			w, _ = flate.NewWriter(res, flate.DefaultCompression)
+		case "/br":
+			res.Header().Set(headers.ContentEncoding, "br")
+			w = brotli.NewWriter(res)
+		case "/zstd":
+			res.Header().Set(headers.ContentEncoding, "zstd")
+			w, _ = zstd.NewWriter(res)
+		case "/stacked":
+			// gzip is applied first, and then br.
+			res.Header().Set(headers.ContentEncoding, "gzip, BR")
+			bw := brotli.NewWriter(res)
+			gw := gzip.NewWriter(bw)
+			gw.Write([]byte(s.body))
+			gw.Close()
+			bw.Close()
+			return
+		case "/empty":
+			res.Header().Set(headers.ContentEncoding, "gzip")
+			res.WriteHeader(http.StatusNoContent)
+			return
+		case "/accept-encoding":
+			res.Write([]byte(req.Header.Get(headers.AcceptEncoding)))
+			return
+		default:
+			res.Write([]byte(s.body))
+			return
+		}
+
+		w.Write([]byte(s.body))
+		w.Close()
+	}))
+}
+
+func (s *StreamSuite) TearDownTest() {
+	s.server.Close()
+}
+
+func (s *StreamSuite) TestStream() {
+	for _, path := range []string{"/", "/gzip", "/zlib", "/deflate", "/br", "/zstd", "/stacked"} {
+		res, err := Get(s.server.URL + path).End()
+		s.Nil(err)
+
+		stream, err := res.Stream()
+		s.Nil(err)
+
+		b, err := ioutil.ReadAll(stream)
+		s.Nil(err)
+		s.Nil(stream.Close())
+		s.Equal(s.body, string(b))
+
+		_, err = res.Stream()
+		s.Equal(ErrBodyStreamed, err)
+
+		_, err = res.Raw()
+		s.Equal(ErrBodyStreamed, err)
+
+		_, err = res.Text()
+		s.Equal(ErrBodyStreamed, err)
+	}
+}
+
+func (s *StreamSuite) TestStreamAfterRaw() {
+	res, err := Get(s.server.URL+"/gzip").Set(headers.AcceptEncoding, "gzip").End()
+	s.Nil(err)
+
+	_, err = res.Raw()
+	s.Nil(err)
+
+	for i := 0; i < 2; i++ {
+		stream, err := res.Stream()
+		s.Nil(err)
+
+		b, err := ioutil.ReadAll(stream)
+		s.Nil(err)
+		s.Equal(s.body, string(b))
+	}
+}
+
+func (s *StreamSuite) TestWriteTo() {
+	res, err := Get(s.server.URL + "/gzip").End()
+	s.Nil(err)
+
+	buf := bytes.NewBuffer(nil)
+	n, err := res.WriteTo(buf)
+
+	s.Nil(err)
+	s.Equal(int64(len(s.body)), n)
+	s.Equal(s.body, buf.String())
+}
+
+func (s *StreamSuite) TestSaveTo() {
+	dir, err := ioutil.TempDir("", "request")
+	s.Nil(err)
+	defer os.RemoveAll(dir)
+
+	res, err := Get(s.server.URL+"/gzip").Set(headers.AcceptEncoding, "gzip").End()
+	s.Nil(err)
+
+	path := filepath.Join(dir, "body")
+
+	s.Nil(res.SaveTo(path))
+
+	b, err := ioutil.ReadFile(path)
+	s.Nil(err)
+	s.Equal(s.body, string(b))
+
+	s.NotNil(res.SaveTo(filepath.Join(dir, "missing", "body")))
+}
+
+func (s *StreamSuite) TestContentEncodings() {
+	for _, path := range []string{"/br", "/zstd", "/stacked"} {
+		text, err := Get(s.server.URL + path).Text()
+
+		s.Nil(err)
+		s.True(text == s.body, path)
+	}
+}
+
+func (s *StreamSuite) TestAcceptEncoding() {
+	text, err := Get(s.server.URL + "/accept-encoding").Text()
+
+	s.Nil(err)
+	s.Equal("gzip, deflate, br, zstd", text)
+
+	text, err = Get(s.server.URL+"/accept-encoding").Set(headers.AcceptEncoding, "gzip").Text()
+
+	s.Nil(err)
+	s.Equal("gzip", text)
+}
+
+func (s *StreamSuite) TestEmptyEncodedBody() {
+	for _, method := range []string{http.MethodGet, http.MethodHead} {
+		res, err := New().To(method, s.server.URL+"/empty").End()
+		s.Nil(err)
+
+		text, err := res.Text()
+
+		s.Nil(err)
+		s.Equal("", text)
+	}
+
+	res, err := Get(s.server.URL + "/empty").End()
+	s.Nil(err)
+
+	stream, err := res.Stream()
+	s.Nil(err)
+
+	b, err := ioutil.ReadAll(stream)
+
+	s.Nil(err)
+	s.Empty(b)
+	s.Nil(stream.Close())
+}
+
+func (s *StreamSuite) TestDecompressedBody() {
+	res, err := Get(s.server.URL + "/gzip").End()
+	s.Nil(err)
+
+	b, err := ioutil.ReadAll(res.Body)
+
+	s.Nil(err)
+	s.Equal(s.body, string(b))
+	s.True(res.Uncompressed)
+	s.Equal("", res.Header.Get(headers.ContentEncoding))
+	s.Equal(int64(-1), res.ContentLength)
+
+	res, err = Get(s.server.URL + "/br").End()
+	s.Nil(err)
+
+	raw, err := res.Raw()
+
+	s.Nil(err)
+	s.Equal(s.body, string(raw))
+}
+
+func (s *StreamSuite) TestRawEncodedBody() {
+	res, err := Get(s.server.URL+"/gzip").Set(headers.AcceptEncoding, "gzip").End()
+	s.Nil(err)
+
+	raw, err := res.Raw()
+	s.Nil(err)
+
+	content, err := res.Content()
+	s.Nil(err)
+
+	s.Equal(s.body, string(content))
+
+	zr, err := gzip.NewReader(bytes.NewReader(raw))
+	s.Nil(err)
+
+	b, err := ioutil.ReadAll(zr)
+
+	s.Nil(err)
+	s.Equal(s.body, string(b))
+}
+
+func TestStream(t *testing.T) {
+	suite.Run(t, new(StreamSuite))
+}
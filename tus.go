@@ -0,0 +1,482 @@
+package request
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	tusVersion          = "1.0.0"
+	defaultTusChunkSize = 4 << 20
+)
+
+// TusStore stores the upload URLs of unfinished tus uploads by their
+// fingerprints, so the uploads can be resumed after a failure or a restart.
+type TusStore interface {
+	// Get returns the upload URL of the fingerprint, or "" if there is none.
+	Get(fingerprint string) (string, error)
+	Set(fingerprint, url string) error
+	Delete(fingerprint string) error
+}
+
+type memoryTusStore struct {
+	mu   sync.Mutex
+	urls map[string]string
+}
+
+// NewMemoryTusStore returns a TusStore which keeps the upload URLs in memory,
+// so the uploads can only be resumed by the same process.
+func NewMemoryTusStore() TusStore {
+	return &memoryTusStore{urls: make(map[string]string)}
+}
+
+func (s *memoryTusStore) Get(fingerprint string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.urls[fingerprint], nil
+}
+
+func (s *memoryTusStore) Set(fingerprint, url string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.urls[fingerprint] = url
+
+	return nil
+}
+
+func (s *memoryTusStore) Delete(fingerprint string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.urls, fingerprint)
+
+	return nil
+}
+
+type fileTusStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileTusStore returns a TusStore which keeps the upload URLs in the JSON
+// file at path, so the uploads survive process restarts.
+func NewFileTusStore(path string) TusStore {
+	return &fileTusStore{path: path}
+}
+
+func (s *fileTusStore) load() (map[string]string, error) {
+	urls := make(map[string]string)
+	b, err := ioutil.ReadFile(s.path)
+
+	if os.IsNotExist(err) {
+		return urls, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(b, &urls); err != nil {
+		return nil, err
+	}
+
+	return urls, nil
+}
+
+func (s *fileTusStore) update(fn func(urls map[string]string)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	urls, err := s.load()
+
+	if err != nil {
+		return err
+	}
+
+	fn(urls)
+
+	b, err := json.Marshal(urls)
+
+	if err != nil {
+		return err
+	}
+
+	// Writes to a temporary file first, so the store is never left half
+	// written.
+	tmp := s.path + ".tmp"
+
+	if err = ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, s.path)
+}
+
+func (s *fileTusStore) Get(fingerprint string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	urls, err := s.load()
+
+	if err != nil {
+		return "", err
+	}
+
+	return urls[fingerprint], nil
+}
+
+func (s *fileTusStore) Set(fingerprint, url string) error {
+	return s.update(func(urls map[string]string) {
+		urls[fingerprint] = url
+	})
+}
+
+func (s *fileTusStore) Delete(fingerprint string) error {
+	return s.update(func(urls map[string]string) {
+		delete(urls, fingerprint)
+	})
+}
+
+// Tus uploads files to a server which implements the tus resumable upload
+// protocol (https://tus.io/protocols/resumable-upload). Uploads are created by
+// the creation extension, and the upload URLs are kept in the Store until the
+// uploads finish, so an interrupted upload is resumed from the offset reported
+// by the server instead of being started over.
+type Tus struct {
+	// ChunkSize is the max number of bytes sent by a PATCH request, 4 MiB is
+	// used if it is not positive.
+	ChunkSize int64
+	// Retries is the number of times in a row a failed PATCH request is
+	// resumed without any progress before Upload gives up.
+	Retries int
+	// Checksum is the algorithm of the checksum extension, which is one of
+	// "sha1", "sha256" and "md5". The checksum of each chunk is sent in the
+	// "Upload-Checksum" header if it is not empty.
+	Checksum string
+	// Store stores the upload URLs of the unfinished uploads.
+	Store TusStore
+
+	agent    *Agent
+	endpoint string
+}
+
+// NewTus returns a new instance of Tus, which creates uploads at the
+// endpoint URL with the requests spawned by agent. The endpoint is resolved
+// against the base URL of agent. The chunk size is 4 MiB, a failed PATCH
+// request is resumed 3 times, and the upload URLs are stored in memory.
+func NewTus(agent *Agent, endpoint string) *Tus {
+	if agent == nil {
+		agent = NewAgent()
+	}
+
+	return &Tus{
+		ChunkSize: defaultTusChunkSize,
+		Retries:   3,
+		Store:     NewMemoryTusStore(),
+		agent:     agent,
+		endpoint:  endpoint,
+	}
+}
+
+// UploadFile uploads the file at path, the fingerprint of the upload is made
+// of the path, size and modification time of the file. metadata is sent in
+// the "Upload-Metadata" header, the "filename" key is set to the base name of
+// the file unless it is already set. It returns the upload URL.
+func (t *Tus) UploadFile(ctx context.Context, path string, metadata map[string]string) (string, error) {
+	file, err := os.Open(path)
+
+	if err != nil {
+		return "", err
+	}
+
+	defer file.Close()
+
+	info, err := file.Stat()
+
+	if err != nil {
+		return "", err
+	}
+
+	meta := map[string]string{"filename": info.Name()}
+
+	for k, v := range metadata {
+		meta[k] = v
+	}
+
+	fingerprint := fmt.Sprintf("%s-%d-%d", path, info.Size(), info.ModTime().UnixNano())
+
+	return t.Upload(ctx, fingerprint, file, info.Size(), meta)
+}
+
+// Upload uploads size bytes read from r, and returns the upload URL. If the
+// Store has an upload URL for the fingerprint, the upload is resumed from the
+// offset reported by the server, otherwise a new upload is created.
+func (t *Tus) Upload(ctx context.Context, fingerprint string, r io.ReaderAt, size int64, metadata map[string]string) (string, error) {
+	location, offset, err := t.resume(ctx, fingerprint, size)
+
+	if err != nil {
+		return "", err
+	}
+
+	if location == "" {
+		if location, err = t.create(ctx, size, metadata); err != nil {
+			return "", err
+		}
+
+		if err = t.Store.Set(fingerprint, location); err != nil {
+			return "", err
+		}
+	}
+
+	for failures := 0; offset < size; {
+		n, err := t.patch(ctx, location, r, offset, size)
+
+		if err == nil {
+			offset = n
+			failures = 0
+			continue
+		}
+
+		if failures++; failures > t.Retries || ctx.Err() != nil {
+			return location, err
+		}
+
+		n, err = t.offset(ctx, location)
+
+		if err != nil {
+			return location, err
+		}
+
+		// The server may have saved a part of the failed chunk.
+		if n > offset {
+			failures = 0
+		}
+
+		offset = n
+	}
+
+	if err = t.Store.Delete(fingerprint); err != nil {
+		return location, err
+	}
+
+	return location, nil
+}
+
+// Terminate terminates the upload by the termination extension, so the
+// server frees the resources of it.
+func (t *Tus) Terminate(ctx context.Context, location string) error {
+	res, err := t.agent.Delete(location).
+		Set("Tus-Resumable", tusVersion).
+		EndContext(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if !res.OK() {
+		b, _ := res.Content()
+		return newStatusError(res, b)
+	}
+
+	return nil
+}
+
+// resume returns the upload URL stored for the fingerprint and its offset. It
+// returns "" if there is no upload to resume.
+func (t *Tus) resume(ctx context.Context, fingerprint string, size int64) (string, int64, error) {
+	location, err := t.Store.Get(fingerprint)
+
+	if err != nil || location == "" {
+		return "", 0, err
+	}
+
+	offset, err := t.offset(ctx, location)
+
+	if err == nil && offset > size {
+		err = ErrTusOffsetMismatch
+	}
+
+	if err == nil {
+		return location, offset, nil
+	}
+
+	// The upload is gone or unusable, so it is uploaded again. Other errors,
+	// such as an expired token, keep the upload to resume it later.
+	if statusErr, ok := err.(*StatusError); ok && isTusUploadGone(statusErr.StatusCode) || err == ErrTusOffsetMismatch {
+		return "", 0, t.Store.Delete(fingerprint)
+	}
+
+	return "", 0, err
+}
+
+// isTusUploadGone reports whether the status code of a HEAD request means
+// the upload doesn't exist, as the tus protocol specifies.
+func isTusUploadGone(code int) bool {
+	return code == http.StatusNotFound || code == http.StatusGone || code == http.StatusForbidden
+}
+
+func (t *Tus) create(ctx context.Context, size int64, metadata map[string]string) (string, error) {
+	c := t.agent.Post(t.endpoint).
+		Set("Tus-Resumable", tusVersion).
+		Set("Upload-Length", strconv.FormatInt(size, 10))
+
+	if len(metadata) > 0 {
+		c.Set("Upload-Metadata", encodeTusMetadata(metadata))
+	}
+
+	res, err := c.EndContext(ctx)
+
+	if err != nil {
+		return "", err
+	}
+
+	b, _ := res.Content()
+
+	if res.StatusCode != http.StatusCreated {
+		return "", newStatusError(res, b)
+	}
+
+	location, err := res.Location()
+
+	if err != nil {
+		return "", err
+	}
+
+	return location.String(), nil
+}
+
+// offset returns the offset of the upload reported by the server.
+func (t *Tus) offset(ctx context.Context, location string) (int64, error) {
+	res, err := t.agent.To(http.MethodHead, location).
+		Set("Tus-Resumable", tusVersion).
+		Set("Cache-Control", "no-store").
+		EndContext(ctx)
+
+	if err != nil {
+		return 0, err
+	}
+
+	res.Body.Close()
+
+	if !res.OK() {
+		return 0, newStatusError(res, nil)
+	}
+
+	return tusOffset(res)
+}
+
+// patch sends the chunk of r from offset, and returns the new offset.
+func (t *Tus) patch(ctx context.Context, location string, r io.ReaderAt, offset, size int64) (int64, error) {
+	n := size - offset
+	chunkSize := t.ChunkSize
+
+	if chunkSize <= 0 {
+		chunkSize = defaultTusChunkSize
+	}
+
+	if n > chunkSize {
+		n = chunkSize
+	}
+
+	chunk := make([]byte, n)
+
+	if _, err := r.ReadAt(chunk, offset); err != nil && err != io.EOF {
+		return 0, err
+	}
+
+	c := t.agent.To("PATCH", location).
+		Type("application/offset+octet-stream").
+		Set("Tus-Resumable", tusVersion).
+		Set("Upload-Offset", strconv.FormatInt(offset, 10))
+
+	if t.Checksum != "" {
+		h, err := tusHash(t.Checksum)
+
+		if err != nil {
+			return 0, err
+		}
+
+		h.Write(chunk)
+		c.Set("Upload-Checksum", t.Checksum+" "+base64.StdEncoding.EncodeToString(h.Sum(nil)))
+	}
+
+	res, err := c.Body(bytes.NewReader(chunk)).EndContext(ctx)
+
+	if err != nil {
+		return 0, err
+	}
+
+	b, _ := res.Content()
+
+	if res.StatusCode != http.StatusNoContent && res.StatusCode != http.StatusOK {
+		return 0, newStatusError(res, b)
+	}
+
+	newOffset, err := tusOffset(res)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if newOffset <= offset || newOffset > offset+n {
+		return 0, ErrTusOffsetMismatch
+	}
+
+	return newOffset, nil
+}
+
+func tusOffset(res *Response) (int64, error) {
+	offset, err := strconv.ParseInt(res.Header.Get("Upload-Offset"), 10, 64)
+
+	if err != nil || offset < 0 {
+		return 0, ErrTusOffsetMismatch
+	}
+
+	return offset, nil
+}
+
+func tusHash(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case "sha1":
+		return sha1.New(), nil
+	case "sha256":
+		return sha256.New(), nil
+	case "md5":
+		return md5.New(), nil
+	}
+
+	return nil, fmt.Errorf("request: unsupported tus checksum algorithm %q", algorithm)
+}
+
+// encodeTusMetadata encodes metadata as the value of the "Upload-Metadata"
+// header, the values are base64 encoded and the keys are sorted.
+func encodeTusMetadata(metadata map[string]string) string {
+	pairs := make([]string, 0, len(metadata))
+
+	for k, v := range metadata {
+		pairs = append(pairs, k+" "+base64.StdEncoding.EncodeToString([]byte(v)))
+	}
+
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,288 @@
+package request
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type tusUpload struct {
+	length   int64
+	data     []byte
+	metadata string
+}
+
+// tusHandler is a minimal tus server with the creation, checksum and
+// termination extensions.
+type tusHandler struct {
+	mu      sync.Mutex
+	uploads map[string]*tusUpload
+	methods []string
+	// fail makes the PATCH requests fail after saving a half of the chunk.
+	fail func(offset int64) bool
+	// reject makes all the requests fail with the status code if it is set.
+	reject int
+}
+
+func (h *tusHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.methods = append(h.methods, req.Method)
+
+	if h.reject != 0 {
+		res.WriteHeader(h.reject)
+		return
+	}
+
+	if req.Header.Get("Tus-Resumable") != tusVersion {
+		res.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
+
+	res.Header().Set("Tus-Resumable", tusVersion)
+
+	if req.Method == http.MethodPost {
+		length, err := strconv.ParseInt(req.Header.Get("Upload-Length"), 10, 64)
+
+		if err != nil {
+			res.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		id := strconv.Itoa(len(h.uploads) + 1)
+		h.uploads[id] = &tusUpload{length: length, metadata: req.Header.Get("Upload-Metadata")}
+
+		res.Header().Set("Location", "/files/"+id)
+		res.WriteHeader(http.StatusCreated)
+		return
+	}
+
+	upload := h.uploads[strings.TrimPrefix(req.URL.Path, "/files/")]
+
+	if upload == nil {
+		res.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch req.Method {
+	case http.MethodHead:
+		res.Header().Set("Upload-Offset", strconv.Itoa(len(upload.data)))
+		res.Header().Set("Upload-Length", strconv.FormatInt(upload.length, 10))
+	case "PATCH":
+		offset, _ := strconv.ParseInt(req.Header.Get("Upload-Offset"), 10, 64)
+
+		if req.Header.Get("Content-Type") != "application/offset+octet-stream" {
+			res.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+
+		if offset != int64(len(upload.data)) {
+			res.WriteHeader(http.StatusConflict)
+			return
+		}
+
+		b, _ := ioutil.ReadAll(req.Body)
+
+		if checksum := req.Header.Get("Upload-Checksum"); checksum != "" {
+			sum := sha1.Sum(b)
+
+			if checksum != "sha1 "+base64.StdEncoding.EncodeToString(sum[:]) {
+				res.WriteHeader(460)
+				return
+			}
+		}
+
+		if h.fail != nil && h.fail(offset) {
+			upload.data = append(upload.data, b[:len(b)/2]...)
+			res.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		upload.data = append(upload.data, b...)
+		res.Header().Set("Upload-Offset", strconv.Itoa(len(upload.data)))
+		res.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		delete(h.uploads, strings.TrimPrefix(req.URL.Path, "/files/"))
+		res.WriteHeader(http.StatusNoContent)
+	default:
+		res.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+type TusSuite struct {
+	suite.Suite
+
+	content []byte
+	dir     string
+	path    string
+	handler *tusHandler
+	server  *httptest.Server
+	tus     *Tus
+}
+
+func (s *TusSuite) SetupTest() {
+	var err error
+	s.dir, err = ioutil.TempDir("", "request")
+	s.Nil(err)
+
+	s.content = []byte(strings.Repeat("0123456789", 1000))
+	s.path = filepath.Join(s.dir, "file.txt")
+	s.Nil(ioutil.WriteFile(s.path, s.content, 0644))
+
+	s.handler = &tusHandler{uploads: make(map[string]*tusUpload)}
+	s.server = httptest.NewServer(s.handler)
+
+	s.tus = NewTus(NewAgent().BaseURL(s.server.URL), "/files/")
+	s.tus.ChunkSize = 3000
+}
+
+func (s *TusSuite) TearDownTest() {
+	s.server.Close()
+	os.RemoveAll(s.dir)
+}
+
+func (s *TusSuite) upload(location string) *tusUpload {
+	s.handler.mu.Lock()
+	defer s.handler.mu.Unlock()
+
+	return s.handler.uploads[strings.TrimPrefix(location, s.server.URL+"/files/")]
+}
+
+func (s *TusSuite) TestUpload() {
+	s.tus.Checksum = "sha1"
+
+	location, err := s.tus.UploadFile(context.Background(), s.path, map[string]string{"type": "text/plain"})
+
+	s.Nil(err)
+	s.Equal(s.server.URL+"/files/1", location)
+	s.Equal([]string{"POST", "PATCH", "PATCH", "PATCH", "PATCH"}, s.handler.methods)
+
+	upload := s.upload(location)
+	s.True(bytes.Equal(s.content, upload.data))
+	s.Equal(fmt.Sprintf("filename %s,type %s",
+		base64.StdEncoding.EncodeToString([]byte("file.txt")),
+		base64.StdEncoding.EncodeToString([]byte("text/plain"))), upload.metadata)
+}
+
+func (s *TusSuite) TestDefaultChunkSize() {
+	s.tus.ChunkSize = 0
+
+	location, err := s.tus.UploadFile(context.Background(), s.path, nil)
+
+	s.Nil(err)
+	s.Equal([]string{"POST", "PATCH"}, s.handler.methods)
+	s.True(bytes.Equal(s.content, s.upload(location).data))
+}
+
+func (s *TusSuite) TestResumeAfterFailure() {
+	failed := make(map[int64]bool)
+	s.handler.fail = func(offset int64) bool {
+		fail := !failed[offset]
+		failed[offset] = true
+		return fail
+	}
+
+	location, err := s.tus.UploadFile(context.Background(), s.path, nil)
+
+	s.Nil(err)
+	s.True(bytes.Equal(s.content, s.upload(location).data))
+	s.Contains(s.handler.methods, http.MethodHead)
+}
+
+func (s *TusSuite) TestResumeAfterRestart() {
+	s.tus.Store = NewFileTusStore(filepath.Join(s.dir, "uploads.json"))
+	s.tus.Retries = 0
+	s.handler.fail = func(offset int64) bool {
+		return offset > 0
+	}
+
+	location, err := s.tus.UploadFile(context.Background(), s.path, nil)
+
+	s.ErrorIs(err, ErrStatusNotOk)
+	s.Equal(4500, len(s.upload(location).data))
+
+	// A new process with the same store.
+	s.handler.fail = nil
+	s.handler.methods = nil
+
+	tus := NewTus(NewAgent().BaseURL(s.server.URL), "/files/")
+	tus.Store = NewFileTusStore(filepath.Join(s.dir, "uploads.json"))
+
+	resumed, err := tus.UploadFile(context.Background(), s.path, nil)
+
+	s.Nil(err)
+	s.Equal(location, resumed)
+	s.Equal([]string{"HEAD", "PATCH"}, s.handler.methods)
+	s.True(bytes.Equal(s.content, s.upload(location).data))
+
+	b, err := ioutil.ReadFile(filepath.Join(s.dir, "uploads.json"))
+	s.Nil(err)
+	s.Equal("{}", string(b))
+}
+
+func (s *TusSuite) TestUploadGone() {
+	fingerprint := "gone"
+	s.Nil(s.tus.Store.Set(fingerprint, s.server.URL+"/files/404"))
+
+	location, err := s.tus.Upload(context.Background(), fingerprint, bytes.NewReader(s.content), int64(len(s.content)), nil)
+
+	s.Nil(err)
+	s.Equal(s.server.URL+"/files/1", location)
+	s.Equal("HEAD", s.handler.methods[0])
+	s.True(bytes.Equal(s.content, s.upload(location).data))
+}
+
+func (s *TusSuite) TestResumeUnauthorized() {
+	fingerprint := "unauthorized"
+	s.Nil(s.tus.Store.Set(fingerprint, s.server.URL+"/files/1"))
+
+	for _, code := range []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusTooManyRequests} {
+		s.handler.reject = code
+
+		_, err := s.tus.Upload(context.Background(), fingerprint, bytes.NewReader(s.content), int64(len(s.content)), nil)
+
+		s.Equal(code, err.(*StatusError).StatusCode)
+
+		location, err := s.tus.Store.Get(fingerprint)
+
+		s.Nil(err)
+		s.Equal(s.server.URL+"/files/1", location)
+	}
+}
+
+func (s *TusSuite) TestChecksumMismatch() {
+	s.tus.Checksum = "md5"
+	s.tus.Retries = 0
+
+	_, err := s.tus.UploadFile(context.Background(), s.path, nil)
+
+	s.ErrorIs(err, ErrStatusNotOk)
+	s.Equal(460, err.(*StatusError).StatusCode)
+}
+
+func (s *TusSuite) TestTerminate() {
+	location, err := s.tus.UploadFile(context.Background(), s.path, nil)
+	s.Nil(err)
+
+	s.Nil(s.tus.Terminate(context.Background(), location))
+	s.Nil(s.upload(location))
+	s.ErrorIs(s.tus.Terminate(context.Background(), location), ErrStatusNotOk)
+}
+
+func TestTus(t *testing.T) {
+	suite.Run(t, new(TusSuite))
+}
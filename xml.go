@@ -0,0 +1,158 @@
+package request
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"mime"
+	"strings"
+
+	"github.com/go-http-utils/headers"
+	"golang.org/x/net/html/charset"
+)
+
+func init() {
+	RegisterCodec("application/xml", xmlCodec{})
+	RegisterCodec("text/xml", xmlCodec{})
+}
+
+type xmlCodec struct{}
+
+// Marshal marshals v with the XML declaration.
+func (xmlCodec) Marshal(v interface{}) ([]byte, error) {
+	b, err := xml.Marshal(v)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), b...), nil
+}
+
+// Unmarshal unmarshals data, whose encoding is taken from its XML
+// declaration.
+func (xmlCodec) Unmarshal(data []byte, v interface{}) error {
+	return unmarshalXML(data, "", v)
+}
+
+// unmarshalXML unmarshals data which is encoded in the charset label. If
+// label is empty, the encoding is taken from the XML declaration instead.
+func unmarshalXML(data []byte, label string, v interface{}) error {
+	var r io.Reader = bytes.NewReader(data)
+
+	if label == "" {
+		dec := xml.NewDecoder(r)
+		dec.CharsetReader = charset.NewReaderLabel
+
+		return dec.Decode(v)
+	}
+
+	// The charset of the "Content-Type" header takes precedence over the one
+	// of the XML declaration, see RFC 7303.
+	r, err := charset.NewReaderLabel(label, r)
+
+	if err != nil {
+		return err
+	}
+
+	dec := xml.NewDecoder(r)
+	dec.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	return dec.Decode(v)
+}
+
+func isXML(typ string) bool {
+	return typ == "application/xml" || typ == "text/xml" ||
+		strings.HasSuffix(typ, "+xml")
+}
+
+// SendXML sends the body in XML format, body can be anything which can be
+// Marshaled by encoding/xml or just Marshaled XML string. The "Content-Type"
+// is set to "application/xml" unless it is already a XML media type.
+func (c *Client) SendXML(body interface{}) *Client {
+	if c.body != nil || len(c.parts) != 0 {
+		c.err = ErrBodyAlreadySet
+		return c
+	}
+
+	if !isXML(mediaType(c.header.Get(headers.ContentType))) {
+		c.Type("xml")
+	}
+
+	switch body := body.(type) {
+	case string:
+		c.body = bytes.NewBufferString(body)
+	default:
+		b, err := xmlCodec{}.Marshal(body)
+
+		if err != nil {
+			c.err = err
+			return c
+		}
+
+		c.body = bytes.NewReader(b)
+	}
+
+	return c
+}
+
+// XML sends the HTTP request and decodes the reponse body with XML format
+// into v.
+func (c *Client) XML(v interface{}) error {
+	if _, err := c.End(); err != nil {
+		return err
+	}
+
+	return c.res.XML(v)
+}
+
+// XML decodes the reponse body with XML format into v. The body is decoded
+// from the charset of the "Content-Type" header, or of the XML declaration if
+// the header has none. Non-ok responses and Client.ErrorInto are handled in
+// the same way as JSON.
+func (r *Response) XML(v interface{}) error {
+	b, err := r.Content()
+
+	if err != nil {
+		return err
+	}
+
+	typ, params, err := mime.ParseMediaType(r.Header.Get(headers.ContentType))
+
+	if err != nil && err != mime.ErrInvalidMediaParameter {
+		typ = ""
+	}
+
+	if !isXML(typ) {
+		if !r.OK() {
+			return newStatusError(r, b)
+		}
+
+		msg := r.Status
+		if len(b) > 0 {
+			msg = string(b)
+		}
+		return errors.New(msg)
+	}
+
+	if !r.OK() && r.errInto != nil {
+		return r.decodeError(xmlCodec{}, b)
+	}
+
+	if err = unmarshalXML(b, params["charset"], v); err != nil {
+		if !r.OK() {
+			return newStatusError(r, b)
+		}
+
+		return err
+	}
+
+	if !r.OK() {
+		return newStatusError(r, b)
+	}
+
+	return nil
+}
@@ -0,0 +1,135 @@
+package request
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-http-utils/headers"
+	"github.com/stretchr/testify/suite"
+)
+
+type XMLSuite struct {
+	suite.Suite
+
+	server *httptest.Server
+}
+
+type xmlItem struct {
+	XMLName xml.Name `xml:"urn:test item"`
+	Name    string   `xml:"urn:test name"`
+}
+
+func (s *XMLSuite) SetupTest() {
+	s.server = httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/echo":
+			b, _ := ioutil.ReadAll(req.Body)
+
+			res.Header().Set(headers.ContentType, req.Header.Get(headers.ContentType))
+			res.Write(b)
+		case "/latin1":
+			res.Header().Set(headers.ContentType, "text/xml; charset=ISO-8859-1")
+			res.Write([]byte("<item xmlns=\"urn:test\"><name>caf\xe9</name></item>"))
+		case "/declaration":
+			res.Header().Set(headers.ContentType, "application/atom+xml")
+			res.Write([]byte("<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>" +
+				"<t:item xmlns:t=\"urn:test\"><t:name>caf\xe9</t:name></t:item>"))
+		case "/error":
+			res.Header().Set(headers.ContentType, "application/xml")
+			res.WriteHeader(http.StatusBadRequest)
+			res.Write([]byte(`<item xmlns="urn:test"><name>bad</name></item>`))
+		default:
+			res.Write([]byte("not xml"))
+		}
+	}))
+}
+
+func (s *XMLSuite) TearDownTest() {
+	s.server.Close()
+}
+
+func (s *XMLSuite) TestSendXML() {
+	item := new(xmlItem)
+
+	err := Post(s.server.URL + "/echo").
+		SendXML(&xmlItem{Name: "David"}).
+		XML(item)
+
+	s.Nil(err)
+	s.Equal("David", item.Name)
+}
+
+func (s *XMLSuite) TestSendXMLType() {
+	res, err := Post(s.server.URL + "/echo").
+		Type("application/soap+xml").
+		SendXML(`<item xmlns="urn:test"><name>David</name></item>`).
+		End()
+
+	s.Nil(err)
+	s.Equal("application/soap+xml", res.Header.Get(headers.ContentType))
+
+	item := new(xmlItem)
+
+	s.Nil(res.Decode(item))
+	s.Equal("David", item.Name)
+}
+
+func (s *XMLSuite) TestSendWithType() {
+	item := new(xmlItem)
+
+	err := Post(s.server.URL + "/echo").
+		Type("xml").
+		Send(&xmlItem{Name: "David"}).
+		XML(item)
+
+	s.Nil(err)
+	s.Equal("David", item.Name)
+}
+
+func (s *XMLSuite) TestSendXMLAfterSend() {
+	err := Post(s.server.URL + "/echo").
+		Send(true).
+		SendXML(&xmlItem{}).
+		XML(new(xmlItem))
+
+	s.Equal(ErrBodyAlreadySet, err)
+}
+
+func (s *XMLSuite) TestCharset() {
+	item := new(xmlItem)
+
+	s.Nil(Get(s.server.URL + "/latin1").XML(item))
+	s.Equal("café", item.Name)
+
+	item = new(xmlItem)
+
+	s.Nil(Get(s.server.URL + "/declaration").XML(item))
+	s.Equal("café", item.Name)
+}
+
+func (s *XMLSuite) TestNotXML() {
+	err := Get(s.server.URL).XML(new(xmlItem))
+
+	s.EqualError(err, "not xml")
+}
+
+func (s *XMLSuite) TestNotOk() {
+	item := new(xmlItem)
+	err := Get(s.server.URL + "/error").XML(item)
+
+	s.Equal(http.StatusBadRequest, err.(*StatusError).StatusCode)
+	s.Equal("bad", item.Name)
+
+	errItem := new(xmlItem)
+	err = Get(s.server.URL + "/error").ErrorInto(errItem).XML(new(xmlItem))
+
+	s.Equal(errItem, err.(*StatusError).Value)
+	s.Equal("bad", errItem.Name)
+}
+
+func TestXML(t *testing.T) {
+	suite.Run(t, new(XMLSuite))
+}